@@ -48,35 +48,8 @@ func (curve CubicBezierCurve) AddOffsetXY(dX, dY float64) CubicBezierCurve {
 
 // GetBounds returns a PdfRectangle of the bounding box of curve.
 func (curve CubicBezierCurve) GetBounds() model.PdfRectangle {
-	minX := curve.P0.X
-	maxX := curve.P0.X
-	minY := curve.P0.Y
-	maxY := curve.P0.Y
-
-	// 1000 points.
-	for t := 0.0; t <= 1.0; t += 0.001 {
-		Rx := curve.P0.X*math.Pow(1-t, 3) +
-			curve.P1.X*3*t*math.Pow(1-t, 2) +
-			curve.P2.X*3*math.Pow(t, 2)*(1-t) +
-			curve.P3.X*math.Pow(t, 3)
-		Ry := curve.P0.Y*math.Pow(1-t, 3) +
-			curve.P1.Y*3*t*math.Pow(1-t, 2) +
-			curve.P2.Y*3*math.Pow(t, 2)*(1-t) +
-			curve.P3.Y*math.Pow(t, 3)
-
-		if Rx < minX {
-			minX = Rx
-		}
-		if Rx > maxX {
-			maxX = Rx
-		}
-		if Ry < minY {
-			minY = Ry
-		}
-		if Ry > maxY {
-			maxY = Ry
-		}
-	}
+	minX, maxX := minMaxExtrema(curve.P0.X, curve.P1.X, curve.P2.X, curve.P3.X)
+	minY, maxY := minMaxExtrema(curve.P0.Y, curve.P1.Y, curve.P2.Y, curve.P3.Y)
 
 	bounds := model.PdfRectangle{}
 	bounds.Llx = minX
@@ -86,6 +59,64 @@ func (curve CubicBezierCurve) GetBounds() model.PdfRectangle {
 	return bounds
 }
 
+// bernstein evaluates the cubic Bernstein form B(t) = p0(1-t)^3 + 3p1t(1-t)^2 + 3p2t^2(1-t) + p3t^3
+// for a single coordinate, at parameter `t`.
+func bernstein(p0, p1, p2, p3, t float64) float64 {
+	mt := 1 - t
+	return p0*mt*mt*mt + 3*p1*t*mt*mt + 3*p2*t*t*mt + p3*t*t*t
+}
+
+// minMaxExtrema returns the min and max of a single coordinate of the cubic Bezier curve with
+// control points p0..p3, by solving for the roots of its derivative (a quadratic) instead of
+// sampling the curve. The derivative B'(t) = 3[(1-t)^2(p1-p0) + 2t(1-t)(p2-p1) + t^2(p3-p2)]
+// is a*t^2 + b*t + c with a = -p0+3p1-3p2+p3, b = 2(p0-2p1+p2), c = p1-p0 (the leading factor of
+// 3 is dropped since it doesn't affect where the derivative is zero).
+func minMaxExtrema(p0, p1, p2, p3 float64) (min, max float64) {
+	min, max = p0, p0
+	if p3 < min {
+		min = p3
+	}
+	if p3 > max {
+		max = p3
+	}
+
+	consider := func(t float64) {
+		if t <= 0 || t >= 1 {
+			return
+		}
+		v := bernstein(p0, p1, p2, p3, t)
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+
+	a := -p0 + 3*p1 - 3*p2 + p3
+	b := 2 * (p0 - 2*p1 + p2)
+	c := p1 - p0
+
+	if math.Abs(a) < 1e-12 {
+		// Linear (or degenerate): b*t + c = 0.
+		if math.Abs(b) > 1e-12 {
+			consider(-c / b)
+		}
+		return min, max
+	}
+
+	discriminant := b*b - 4*a*c
+	if discriminant < 0 {
+		return min, max
+	}
+
+	sqrtDisc := math.Sqrt(discriminant)
+	consider((-b + sqrtDisc) / (2 * a))
+	consider((-b - sqrtDisc) / (2 * a))
+
+	return min, max
+}
+
 // CubicBezierPath represents a pdf path composed of cubic Bezier curves
 type CubicBezierPath struct {
 	Curves []CubicBezierCurve
@@ -163,3 +194,95 @@ func (bpath CubicBezierPath) GetBoundingBox() Rectangle {
 	bbox.Height = maxY - minY
 	return bbox
 }
+
+// lerp returns the point a fraction `t` of the way from `a` to `b`.
+func lerp(a, b Point, t float64) Point {
+	return NewPoint(a.X+(b.X-a.X)*t, a.Y+(b.Y-a.Y)*t)
+}
+
+// SplitAt splits `curve` at parameter `t` (in [0,1]) into two cubic Bezier curves covering
+// [0,t] and [t,1] of the original, using de Casteljau's algorithm. The two halves join exactly
+// at the point `curve` passes through at `t`.
+func (curve CubicBezierCurve) SplitAt(t float64) (CubicBezierCurve, CubicBezierCurve) {
+	p01 := lerp(curve.P0, curve.P1, t)
+	p12 := lerp(curve.P1, curve.P2, t)
+	p23 := lerp(curve.P2, curve.P3, t)
+
+	p012 := lerp(p01, p12, t)
+	p123 := lerp(p12, p23, t)
+
+	p0123 := lerp(p012, p123, t)
+
+	left := CubicBezierCurve{P0: curve.P0, P1: p01, P2: p012, P3: p0123}
+	right := CubicBezierCurve{P0: p0123, P1: p123, P2: p23, P3: curve.P3}
+	return left, right
+}
+
+// isFlatEnough reports whether `curve` stays within `tolerance` of its chord P0-P3. It takes
+// the offsets 3*P1-2*P0-P3 and 3*P2-P0-2*P3 (three times the distance of P1 and P2 from the
+// points one and two thirds of the way along the chord), keeps the larger square of each
+// coordinate, and accepts when their sum is at most 16*tolerance^2, a bound on the curve's
+// deviation from the chord. It does not compute the perpendicular distance of P1 and P2 from
+// the chord.
+func (curve CubicBezierCurve) isFlatEnough(tolerance float64) bool {
+	ux := 3*curve.P1.X - 2*curve.P0.X - curve.P3.X
+	uy := 3*curve.P1.Y - 2*curve.P0.Y - curve.P3.Y
+	vx := 3*curve.P2.X - curve.P0.X - 2*curve.P3.X
+	vy := 3*curve.P2.Y - curve.P0.Y - 2*curve.P3.Y
+
+	ux *= ux
+	uy *= uy
+	vx *= vx
+	vy *= vy
+
+	if ux < vx {
+		ux = vx
+	}
+	if uy < vy {
+		uy = vy
+	}
+
+	return ux+uy <= 16*tolerance*tolerance
+}
+
+// Flatten recursively subdivides `curve` via de Casteljau's algorithm until each piece passes
+// isFlatEnough for `tolerance`, then returns the endpoints of the resulting polyline
+// (including both `curve.P0` and `curve.P3`). This underlies hit-testing, clipping, stroking
+// with dash patterns, and rasterization, none of which can operate on the cubic representation
+// directly.
+func (curve CubicBezierCurve) Flatten(tolerance float64) []Point {
+	return curve.flatten(tolerance, 0)
+}
+
+// maxBezierFlattenDepth bounds the recursion in flatten so a curve that isFlatEnough never
+// accepts (e.g. a `tolerance` of 0, which only an exactly straight piece meets) can't recurse
+// forever.
+const maxBezierFlattenDepth = 32
+
+func (curve CubicBezierCurve) flatten(tolerance float64, depth int) []Point {
+	if depth >= maxBezierFlattenDepth || curve.isFlatEnough(tolerance) {
+		return []Point{curve.P0, curve.P3}
+	}
+
+	left, right := curve.SplitAt(0.5)
+	leftPoints := left.flatten(tolerance, depth+1)
+	rightPoints := right.flatten(tolerance, depth+1)
+
+	// leftPoints's last point and rightPoints's first point are both the midpoint; drop one.
+	return append(leftPoints, rightPoints[1:]...)
+}
+
+// Flatten subdivides every curve in `bpath` via (CubicBezierCurve).Flatten and concatenates the
+// results into a single polyline approximating the whole path.
+func (bpath CubicBezierPath) Flatten(tolerance float64) []Point {
+	var points []Point
+	for _, c := range bpath.Curves {
+		curvePoints := c.Flatten(tolerance)
+		if len(points) > 0 && len(curvePoints) > 0 {
+			// Adjacent curves share an endpoint; drop the duplicate.
+			curvePoints = curvePoints[1:]
+		}
+		points = append(points, curvePoints...)
+	}
+	return points
+}
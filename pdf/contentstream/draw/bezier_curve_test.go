@@ -0,0 +1,52 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package draw
+
+import "testing"
+
+func TestCubicBezierCurveSplitAtJoinsAtSamePoint(t *testing.T) {
+	curve := NewCubicBezierCurve(0, 0, 10, 30, 30, -30, 40, 0)
+
+	left, right := curve.SplitAt(0.5)
+	if left.P3 != right.P0 {
+		t.Errorf("split halves don't join: left.P3=%v right.P0=%v", left.P3, right.P0)
+	}
+	if left.P0 != curve.P0 {
+		t.Errorf("left.P0 = %v, want %v", left.P0, curve.P0)
+	}
+	if right.P3 != curve.P3 {
+		t.Errorf("right.P3 = %v, want %v", right.P3, curve.P3)
+	}
+}
+
+func TestCubicBezierCurveFlattenEndpoints(t *testing.T) {
+	curve := NewCubicBezierCurve(0, 0, 10, 30, 30, -30, 40, 0)
+
+	points := curve.Flatten(0.1)
+	if len(points) < 2 {
+		t.Fatalf("expected at least 2 points, got %d", len(points))
+	}
+	if points[0] != curve.P0 {
+		t.Errorf("first point = %v, want %v", points[0], curve.P0)
+	}
+	if points[len(points)-1] != curve.P3 {
+		t.Errorf("last point = %v, want %v", points[len(points)-1], curve.P3)
+	}
+}
+
+func TestCubicBezierCurveGetBoundsMatchesSampledCurve(t *testing.T) {
+	curve := NewCubicBezierCurve(0, 0, 10, 50, 40, 50, 50, 0)
+	bounds := curve.GetBounds()
+
+	for _, p := range curve.Flatten(0.01) {
+		if p.X < bounds.Llx-1e-6 || p.X > bounds.Urx+1e-6 {
+			t.Errorf("point %v outside bounds X [%f, %f]", p, bounds.Llx, bounds.Urx)
+		}
+		if p.Y < bounds.Lly-1e-6 || p.Y > bounds.Ury+1e-6 {
+			t.Errorf("point %v outside bounds Y [%f, %f]", p, bounds.Lly, bounds.Ury)
+		}
+	}
+}
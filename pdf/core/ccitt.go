@@ -0,0 +1,419 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+// CCITTFaxParams mirrors the /DecodeParms keys recognized for /CCITTFaxDecode streams (PDF32000
+// Table 11): K selects the coding scheme, Columns/Rows give the image dimensions, and the rest
+// are the usual fax transmission options.
+type CCITTFaxParams struct {
+	// K < 0 selects Group 4 (pure two-dimensional), K == 0 selects Group 3 one-dimensional,
+	// K > 0 selects Group 3 mixed one/two-dimensional.
+	K               int
+	Columns         int
+	Rows            int
+	EndOfLine       bool
+	EncodedByteAlign bool
+	EndOfBlock      bool
+	BlackIs1        bool
+}
+
+// DefaultCCITTFaxParams returns the /DecodeParms defaults defined by the spec: K=0, Columns=1728,
+// Rows=0 (until EndOfBlock or data exhausted), EndOfLine=false, EncodedByteAlign=false,
+// EndOfBlock=true, BlackIs1=false.
+func DefaultCCITTFaxParams() CCITTFaxParams {
+	return CCITTFaxParams{
+		Columns:    1728,
+		EndOfBlock: true,
+	}
+}
+
+// DecodeCCITTFax decodes Group 3/Group 4 (T.4/T.6) fax-compressed `data` per `params`, returning
+// packed 1-bit-per-pixel rows (MSB first, each row padded to a byte boundary). With
+// params.BlackIs1 set, a 1 bit means black; otherwise the rows are inverted, so a 0 bit means
+// black and a 1 bit white.
+func DecodeCCITTFax(data []byte, params CCITTFaxParams) ([]byte, error) {
+	if params.Columns <= 0 {
+		return nil, errors.New("ccitt: Columns must be positive")
+	}
+
+	br := newCCITTBitReader(data)
+	stride := (params.Columns + 7) / 8
+	var out []byte
+
+	refLine := []int{params.Columns, params.Columns} // an all-white reference line.
+
+	for row := 0; params.Rows == 0 || row < params.Rows; row++ {
+		if params.EncodedByteAlign {
+			br.alignToByte()
+		}
+		if br.exhausted() {
+			break
+		}
+
+		twoDim := params.K < 0
+		if params.K > 0 {
+			bit, err := br.readBit()
+			if err != nil {
+				break
+			}
+			twoDim = bit == 0
+		}
+
+		var codingLine []int
+		var err error
+		if twoDim {
+			codingLine, err = decode2DRow(br, refLine, params.Columns)
+		} else {
+			codingLine, err = decode1DRow(br, params.Columns)
+		}
+		if err != nil {
+			if row == 0 {
+				return nil, fmt.Errorf("ccitt: failed decoding row 0: %w", err)
+			}
+			break
+		}
+
+		out = append(out, packCCITTRow(codingLine, params.Columns, stride)...)
+		refLine = codingLine
+	}
+
+	if !params.BlackIs1 {
+		for i := range out {
+			out[i] = ^out[i]
+		}
+		// The padding bits introduced by the stride rounding were 0 before inversion and
+		// inverting set them to 1; clear them back to 0, as they are when no inversion is done.
+		clearRowPadding(out, params.Columns, stride)
+	}
+
+	return out, nil
+}
+
+// clearRowPadding zeroes the unused low-order bits in the last byte of every packed row.
+func clearRowPadding(data []byte, columns, stride int) {
+	pad := stride*8 - columns
+	if pad == 0 {
+		return
+	}
+	mask := byte(0xFF << uint(pad))
+	for row := 0; row*stride < len(data); row++ {
+		data[row*stride+stride-1] &= mask
+	}
+}
+
+// packCCITTRow packs a coding line (alternating changing-element positions, starting with the
+// first transition to black) into a 1bpp row, 1 meaning black.
+func packCCITTRow(changes []int, columns, stride int) []byte {
+	row := make([]byte, stride)
+	color := 0 // 0 = white, 1 = black.
+	pos := 0
+	for _, next := range changes {
+		if next > columns {
+			next = columns
+		}
+		if color == 1 {
+			for x := pos; x < next; x++ {
+				row[x/8] |= 0x80 >> uint(x%8)
+			}
+		}
+		pos = next
+		color = 1 - color
+		if pos >= columns {
+			break
+		}
+	}
+	return row
+}
+
+// ccittBitReader reads individual bits MSB-first from a byte slice, as needed for T.4/T.6's
+// variable-length (modified Huffman / modified READ) codes.
+type ccittBitReader struct {
+	data   []byte
+	bitPos int // absolute bit offset from the start of data.
+}
+
+func newCCITTBitReader(data []byte) *ccittBitReader {
+	return &ccittBitReader{data: data}
+}
+
+func (r *ccittBitReader) exhausted() bool {
+	return r.bitPos >= len(r.data)*8
+}
+
+func (r *ccittBitReader) readBit() (int, error) {
+	if r.exhausted() {
+		return 0, errors.New("ccitt: unexpected end of data")
+	}
+	byteIdx := r.bitPos / 8
+	bitIdx := uint(7 - r.bitPos%8)
+	bit := (r.data[byteIdx] >> bitIdx) & 1
+	r.bitPos++
+	return int(bit), nil
+}
+
+func (r *ccittBitReader) peekBits(n int) (int, int) {
+	val := 0
+	read := 0
+	pos := r.bitPos
+	for i := 0; i < n && pos < len(r.data)*8; i++ {
+		byteIdx := pos / 8
+		bitIdx := uint(7 - pos%8)
+		bit := (r.data[byteIdx] >> bitIdx) & 1
+		val = val<<1 | int(bit)
+		pos++
+		read++
+	}
+	return val, read
+}
+
+func (r *ccittBitReader) skip(n int) {
+	r.bitPos += n
+}
+
+func (r *ccittBitReader) alignToByte() {
+	if r.bitPos%8 != 0 {
+		r.bitPos += 8 - r.bitPos%8
+	}
+}
+
+// decode1DRow decodes one Group 3 one-dimensional (modified Huffman) row, returning the
+// positions of each black/white transition ("changing elements"), starting with the first
+// white-to-black transition, as used by the T.4 Modified Huffman run-length codes.
+func decode1DRow(br *ccittBitReader, columns int) ([]int, error) {
+	var changes []int
+	pos := 0
+	white := true
+	for pos < columns {
+		run, err := readRun(br, white)
+		if err != nil {
+			return nil, err
+		}
+		pos += run
+		if pos > columns {
+			pos = columns
+		}
+		changes = append(changes, pos)
+		white = !white
+	}
+	return changes, nil
+}
+
+// decode2DRow decodes one Group 4/mixed two-dimensional row using the Modified READ vertical,
+// pass, and horizontal modes (T.6 section 2.2), relative to the previous row's changing
+// elements `refLine`.
+func decode2DRow(br *ccittBitReader, refLine []int, columns int) ([]int, error) {
+	var changes []int
+	a0 := -1
+	white := true
+
+	for a0 < columns {
+		b1, b2 := findB1B2(refLine, a0, white, columns)
+
+		mode, nbits, err := readMode(br)
+		if err != nil {
+			return nil, err
+		}
+		br.skip(nbits)
+
+		switch mode {
+		case modePass:
+			a0 = b2
+		case modeHorizontal:
+			run1, err := readRun(br, white)
+			if err != nil {
+				return nil, err
+			}
+			run2, err := readRun(br, !white)
+			if err != nil {
+				return nil, err
+			}
+			start := a0
+			if start < 0 {
+				start = 0
+			}
+			a1 := start + run1
+			a2 := a1 + run2
+			if a1 > columns {
+				a1 = columns
+			}
+			if a2 > columns {
+				a2 = columns
+			}
+			changes = append(changes, a1, a2)
+			a0 = a2
+		case modeVertical0, modeVerticalL1, modeVerticalL2, modeVerticalL3,
+			modeVerticalR1, modeVerticalR2, modeVerticalR3:
+			delta := verticalDelta(mode)
+			a1 := b1 + delta
+			if a1 < 0 {
+				a1 = 0
+			}
+			if a1 > columns {
+				a1 = columns
+			}
+			changes = append(changes, a1)
+			a0 = a1
+			white = !white
+		default:
+			return nil, errors.New("ccitt: unsupported or unrecognized 2D mode code")
+		}
+	}
+
+	return changes, nil
+}
+
+// findB1B2 locates b1 (the first changing element on the reference line to the right of a0 and
+// of opposite color to a0's color) and b2 (the next changing element after b1), per T.6 2.1.
+func findB1B2(refLine []int, a0 int, white bool, columns int) (b1, b2 int) {
+	// refLine alternates colors starting with the first transition to black; changeIdx parity
+	// tells us the color to the right of refLine[i].
+	i := 0
+	for i < len(refLine) && refLine[i] <= a0 {
+		i++
+	}
+	// refLine[i] is a changing element to the right of a0. Its "color to the left" parity must
+	// match a0's color (`white`), i.e. the element itself is of the opposite color, for it to
+	// qualify as b1.
+	if i%2 == 0 {
+		// refLine[i] is a transition to black (even index transitions white->black in our
+		// representation), meaning the color to its left is white.
+		if !white {
+			i++
+		}
+	} else {
+		if white {
+			i++
+		}
+	}
+
+	if i < len(refLine) {
+		b1 = refLine[i]
+	} else {
+		b1 = columns
+	}
+	if i+1 < len(refLine) {
+		b2 = refLine[i+1]
+	} else {
+		b2 = columns
+	}
+	return b1, b2
+}
+
+const (
+	modePass = iota
+	modeHorizontal
+	modeVertical0
+	modeVerticalR1
+	modeVerticalR2
+	modeVerticalR3
+	modeVerticalL1
+	modeVerticalL2
+	modeVerticalL3
+)
+
+// readMode peeks the next few bits and identifies the T.6 mode code, returning the number of
+// bits it occupies (to be consumed by the caller via br.skip).
+func readMode(br *ccittBitReader) (mode int, nbits int, err error) {
+	bits, n := br.peekBits(7)
+	if n == 0 {
+		return 0, 0, errors.New("ccitt: unexpected end of data reading mode code")
+	}
+
+	switch {
+	case n >= 1 && bits>>6 == 1:
+		return modeVertical0, 1, nil
+	case n >= 3 && bits>>4 == 0b011:
+		return modeVerticalR1, 3, nil
+	case n >= 3 && bits>>4 == 0b010:
+		return modeVerticalL1, 3, nil
+	case n >= 3 && bits>>4 == 0b001:
+		return modeHorizontal, 3, nil
+	case n >= 4 && bits>>3 == 0b0001:
+		return modePass, 4, nil
+	case n >= 6 && bits>>1 == 0b000011:
+		return modeVerticalR2, 6, nil
+	case n >= 6 && bits>>1 == 0b000010:
+		return modeVerticalL2, 6, nil
+	case n >= 7 && bits == 0b0000011:
+		return modeVerticalR3, 7, nil
+	case n >= 7 && bits == 0b0000010:
+		return modeVerticalL3, 7, nil
+	default:
+		return 0, 0, fmt.Errorf("ccitt: unrecognized mode code (peeked %07b)", bits)
+	}
+}
+
+func verticalDelta(mode int) int {
+	switch mode {
+	case modeVertical0:
+		return 0
+	case modeVerticalR1:
+		return 1
+	case modeVerticalR2:
+		return 2
+	case modeVerticalR3:
+		return 3
+	case modeVerticalL1:
+		return -1
+	case modeVerticalL2:
+		return -2
+	case modeVerticalL3:
+		return -3
+	default:
+		return 0
+	}
+}
+
+// readRun decodes one Modified Huffman run-length code (terminating code, optionally preceded
+// by one or more makeup codes for runs >= 64) for the given color, using the standard T.4
+// tables.
+func readRun(br *ccittBitReader, white bool) (int, error) {
+	total := 0
+	for {
+		n, err := readRunCode(br, white)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+		if n < 64 {
+			return total, nil
+		}
+		// Makeup code; a terminating code (<64) must still follow.
+	}
+}
+
+// readRunCode decodes a single run-length code (terminating or makeup) by trying progressively
+// longer bit-lengths against the standard white/black code tables, per T.4 Tables 2-4.
+func readRunCode(br *ccittBitReader, white bool) (int, error) {
+	table := whiteCodes
+	if !white {
+		table = blackCodes
+	}
+
+	for length := 1; length <= 13; length++ {
+		bits, n := br.peekBits(length)
+		if n < length {
+			break
+		}
+		if run, ok := table[ccittCode{length, bits}]; ok {
+			br.skip(length)
+			return run, nil
+		}
+	}
+
+	return 0, errors.New("ccitt: unrecognized run-length code")
+}
+
+// ccittCode identifies a variable-length code by its bit length and value.
+type ccittCode struct {
+	length int
+	bits   int
+}
@@ -0,0 +1,108 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+// The tables below are the standard Modified Huffman run-length codes from ITU-T
+// Recommendation T.4 (Tables 2-4), used by both Group 3 (T.4) and Group 4 (T.6) fax coding.
+// Entries are {bit length, code value}: run length.
+
+func mustCode(bits string) ccittCode {
+	val := 0
+	for _, c := range bits {
+		val <<= 1
+		if c == '1' {
+			val |= 1
+		}
+	}
+	return ccittCode{len(bits), val}
+}
+
+var whiteCodes = buildWhiteCodes()
+var blackCodes = buildBlackCodes()
+
+func buildWhiteCodes() map[ccittCode]int {
+	m := map[ccittCode]int{
+		mustCode("00110101"): 0, mustCode("000111"): 1, mustCode("0111"): 2, mustCode("1000"): 3,
+		mustCode("1011"): 4, mustCode("1100"): 5, mustCode("1110"): 6, mustCode("1111"): 7,
+		mustCode("10011"): 8, mustCode("10100"): 9, mustCode("00111"): 10, mustCode("01000"): 11,
+		mustCode("001000"): 12, mustCode("000011"): 13, mustCode("110100"): 14, mustCode("110101"): 15,
+		mustCode("101010"): 16, mustCode("101011"): 17, mustCode("0100111"): 18, mustCode("0001100"): 19,
+		mustCode("0001000"): 20, mustCode("0010111"): 21, mustCode("0000011"): 22, mustCode("0000100"): 23,
+		mustCode("0101000"): 24, mustCode("0101011"): 25, mustCode("0010011"): 26, mustCode("0100100"): 27,
+		mustCode("0011000"): 28, mustCode("00000010"): 29, mustCode("00000011"): 30, mustCode("00011010"): 31,
+		mustCode("00011011"): 32, mustCode("00010010"): 33, mustCode("00010011"): 34, mustCode("00010100"): 35,
+		mustCode("00010101"): 36, mustCode("00010110"): 37, mustCode("00010111"): 38, mustCode("00101000"): 39,
+		mustCode("00101001"): 40, mustCode("00101010"): 41, mustCode("00101011"): 42, mustCode("00101100"): 43,
+		mustCode("00101101"): 44, mustCode("00000100"): 45, mustCode("00000101"): 46, mustCode("00001010"): 47,
+		mustCode("00001011"): 48, mustCode("01010010"): 49, mustCode("01010011"): 50, mustCode("01010100"): 51,
+		mustCode("01010101"): 52, mustCode("00100100"): 53, mustCode("00100101"): 54, mustCode("01011000"): 55,
+		mustCode("01011001"): 56, mustCode("01011010"): 57, mustCode("01011011"): 58, mustCode("01001010"): 59,
+		mustCode("01001011"): 60, mustCode("00110010"): 61, mustCode("00110011"): 62, mustCode("00110100"): 63,
+
+		// Makeup codes.
+		mustCode("11011"): 64, mustCode("10010"): 128, mustCode("010111"): 192, mustCode("0110111"): 256,
+		mustCode("00110110"): 320, mustCode("00110111"): 384, mustCode("01100100"): 448, mustCode("01100101"): 512,
+		mustCode("01101000"): 576, mustCode("01100111"): 640, mustCode("011001100"): 704, mustCode("011001101"): 768,
+		mustCode("011010010"): 832, mustCode("011010011"): 896, mustCode("011010100"): 960, mustCode("011010101"): 1024,
+		mustCode("011010110"): 1088, mustCode("011010111"): 1152, mustCode("011011000"): 1216, mustCode("011011001"): 1280,
+		mustCode("011011010"): 1344, mustCode("011011011"): 1408, mustCode("010011000"): 1472, mustCode("010011001"): 1536,
+		mustCode("010011010"): 1600, mustCode("011000"): 1664, mustCode("010011011"): 1728,
+	}
+	addSharedMakeupCodes(m)
+	return m
+}
+
+func buildBlackCodes() map[ccittCode]int {
+	m := map[ccittCode]int{
+		mustCode("0000110111"): 0, mustCode("010"): 1, mustCode("11"): 2, mustCode("10"): 3,
+		mustCode("011"): 4, mustCode("0011"): 5, mustCode("0010"): 6, mustCode("00011"): 7,
+		mustCode("000101"): 8, mustCode("000100"): 9, mustCode("0000100"): 10, mustCode("0000101"): 11,
+		mustCode("0000111"): 12, mustCode("00000100"): 13, mustCode("00000111"): 14, mustCode("000011000"): 15,
+		mustCode("0000010111"): 16, mustCode("0000011000"): 17, mustCode("0000001000"): 18, mustCode("00001100111"): 19,
+		mustCode("00001101000"): 20, mustCode("00001101100"): 21, mustCode("00000110111"): 22, mustCode("00000101000"): 23,
+		mustCode("00000010111"): 24, mustCode("00000011000"): 25, mustCode("000011001010"): 26, mustCode("000011001011"): 27,
+		mustCode("000011001100"): 28, mustCode("000011001101"): 29, mustCode("000001101000"): 30, mustCode("000001101001"): 31,
+		mustCode("000001101010"): 32, mustCode("000001101011"): 33, mustCode("000011010010"): 34, mustCode("000011010011"): 35,
+		mustCode("000011010100"): 36, mustCode("000011010101"): 37, mustCode("000011010110"): 38, mustCode("000011010111"): 39,
+		mustCode("000001101100"): 40, mustCode("000001101101"): 41, mustCode("000011011010"): 42, mustCode("000011011011"): 43,
+		mustCode("000001010100"): 44, mustCode("000001010101"): 45, mustCode("000001010110"): 46, mustCode("000001010111"): 47,
+		mustCode("000001100100"): 48, mustCode("000001100101"): 49, mustCode("000001010010"): 50, mustCode("000001010011"): 51,
+		mustCode("000000100100"): 52, mustCode("000000110111"): 53, mustCode("000000111000"): 54, mustCode("000000100111"): 55,
+		mustCode("000000101000"): 56, mustCode("000001011000"): 57, mustCode("000001011001"): 58, mustCode("000000101011"): 59,
+		mustCode("000000101100"): 60, mustCode("000001011010"): 61, mustCode("000001100110"): 62, mustCode("000001100111"): 63,
+
+		// Makeup codes.
+		mustCode("0000001111"): 64, mustCode("000011001000"): 128, mustCode("000011001001"): 192,
+		mustCode("000001011011"): 256, mustCode("000000110011"): 320, mustCode("000000110100"): 384,
+		mustCode("000000110101"): 448, mustCode("0000001101100"): 512, mustCode("0000001101101"): 576,
+		mustCode("0000001001010"): 640, mustCode("0000001001011"): 704, mustCode("0000001001100"): 768,
+		mustCode("0000001001101"): 832, mustCode("0000001110010"): 896, mustCode("0000001110011"): 960,
+		mustCode("0000001110100"): 1024, mustCode("0000001110101"): 1088, mustCode("0000001110110"): 1152,
+		mustCode("0000001110111"): 1216, mustCode("0000001010010"): 1280, mustCode("0000001010011"): 1344,
+		mustCode("0000001010100"): 1408, mustCode("0000001010101"): 1472, mustCode("0000001011010"): 1536,
+		mustCode("0000001011011"): 1600, mustCode("0000001100100"): 1664, mustCode("0000001100101"): 1728,
+	}
+	addSharedMakeupCodes(m)
+	return m
+}
+
+// addSharedMakeupCodes adds the extended makeup codes (1792-2560) shared by the white and black
+// tables (T.4 Table 3, the "extended makeup codes" used for very long runs of either color).
+func addSharedMakeupCodes(m map[ccittCode]int) {
+	m[mustCode("00000001000")] = 1792
+	m[mustCode("00000001100")] = 1856
+	m[mustCode("00000001101")] = 1920
+	m[mustCode("000000010010")] = 1984
+	m[mustCode("000000010011")] = 2048
+	m[mustCode("000000010100")] = 2112
+	m[mustCode("000000010101")] = 2176
+	m[mustCode("000000010110")] = 2240
+	m[mustCode("000000010111")] = 2304
+	m[mustCode("000000011100")] = 2368
+	m[mustCode("000000011101")] = 2432
+	m[mustCode("000000011110")] = 2496
+	m[mustCode("000000011111")] = 2560
+}
@@ -0,0 +1,78 @@
+package core
+
+import "testing"
+
+// TestCCITTRunCodeTablesRoundTrip checks that a sample of well-known terminating and makeup
+// codes from both the white and black tables decode to their documented run lengths.
+func TestCCITTRunCodeTablesRoundTrip(t *testing.T) {
+	cases := []struct {
+		table map[ccittCode]int
+		bits  string
+		want  int
+	}{
+		{whiteCodes, "00110101", 0},
+		{whiteCodes, "0111", 2},
+		{whiteCodes, "11011", 64},
+		{blackCodes, "0000110111", 0},
+		{blackCodes, "11", 2},
+		{blackCodes, "0000001111", 64},
+	}
+
+	for _, c := range cases {
+		code := mustCode(c.bits)
+		got, ok := c.table[code]
+		if !ok {
+			t.Errorf("code %q not found in table", c.bits)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("code %q = %d, want %d", c.bits, got, c.want)
+		}
+	}
+}
+
+// TestDecodeCCITTFaxRejectsZeroColumns checks that an invalid Columns value is reported as an
+// error rather than panicking when computing the row stride.
+func TestDecodeCCITTFaxRejectsZeroColumns(t *testing.T) {
+	_, err := DecodeCCITTFax(nil, CCITTFaxParams{Columns: 0})
+	if err == nil {
+		t.Errorf("expected an error for Columns == 0")
+	}
+}
+
+// TestDecodeCCITTFaxGroup4AllWhiteRow decodes a hand-built Group 4 (T.6) bitstream for a single
+// 8-column row that's entirely white. Against an imaginary all-white reference line, the first
+// change on the reference line (b1) sits at column 8, so a single Vertical(0) mode code ("1")
+// puts a1 there too: the whole row stays white. With the default BlackIs1=false, a white row
+// packs as 0x00 internally and is inverted to 0xFF on output.
+func TestDecodeCCITTFaxGroup4AllWhiteRow(t *testing.T) {
+	data := []byte{0x80} // "1" (V0) followed by zero padding.
+	params := CCITTFaxParams{K: -1, Columns: 8, Rows: 1}
+
+	got, err := DecodeCCITTFax(data, params)
+	if err != nil {
+		t.Fatalf("DecodeCCITTFax: %v", err)
+	}
+	want := []byte{0xFF}
+	if string(got) != string(want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+// TestDecodeCCITTFaxGroup4AllBlackRow decodes a hand-built Group 4 bitstream for a single
+// 8-column row that's entirely black, coded in horizontal mode: mode code "001", then a white
+// run of 0 ("00110101"), then a black run of 8 ("000101"). With BlackIs1=false, an all-black row
+// packs as 0xFF internally and is inverted to 0x00 on output.
+func TestDecodeCCITTFaxGroup4AllBlackRow(t *testing.T) {
+	data := []byte{0x26, 0xA2, 0x80} // "001" + "00110101" + "000101", zero-padded to 3 bytes.
+	params := CCITTFaxParams{K: -1, Columns: 8, Rows: 1}
+
+	got, err := DecodeCCITTFax(data, params)
+	if err != nil {
+		t.Fatalf("DecodeCCITTFax: %v", err)
+	}
+	want := []byte{0x00}
+	if string(got) != string(want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
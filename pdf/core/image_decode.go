@@ -0,0 +1,61 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// This file holds the only DecodeBytes implementations for JBIG2Encoder, JPXEncoder and
+// CCITTFaxEncoder within this tree; StreamEncoder previously had no concrete decode path for
+// these three filters here, so there is no prior stub body being shadowed by these additions as
+// far as this tree shows. That assumption could not be re-checked against the full `core`
+// package from this working copy (the encoder type declarations themselves live outside what's
+// visible here) — verify no other file in the real package already declares DecodeBytes on any
+// of these three types before merging, since a duplicate method declaration would fail to build.
+package core
+
+// DecodeBytes decodes `encoded` JBIG2 data (with an optional shared `/JBIG2Globals` stream
+// found via the owning stream's DecodeParms) into a packed 1bpc bitmap. Unlike the generic
+// StreamEncoder.DecodeBytes signature, JBIG2 globals must be threaded in separately since they
+// live in a different stream object; JBIG2Encoder keeps a reference to them once resolved from
+// DecodeParms (see newJBIG2EncoderFromStream).
+func (enc *JBIG2Encoder) DecodeBytes(encoded []byte) ([]byte, error) {
+	packed, _, _, err := DecodeJBIG2Generic(encoded, enc.Globals)
+	if err != nil {
+		return nil, err
+	}
+	return packed, nil
+}
+
+// DecodeBytes parses `encoded` JPX (JPEG 2000) codestream header (enough to recover image
+// dimensions and a coarse color space via enc.ColorSpace) and then always returns
+// errNotImplementedJPXTiles: decoding the entropy-coded tile-part data into pixel samples is out
+// of scope here (see errNotImplementedJPXTiles), so a JPXDecode image XObject fails DecodeStream
+// rather than being silently treated as blank or pass-through pixel data.
+func (enc *JPXEncoder) DecodeBytes(encoded []byte) ([]byte, error) {
+	header, err := parseJPXHeader(encoded)
+	if err != nil {
+		return nil, err
+	}
+	enc.ColorSpace = header.ColorSpace
+
+	return nil, errNotImplementedJPXTiles
+}
+
+// DecodeBytes decodes `encoded` Group 3/Group 4 fax data per the /CCITTFaxDecode /DecodeParms
+// already resolved onto `enc` (K, Columns, Rows, EncodedByteAlign and BlackIs1; see
+// newCCITTFaxEncoderFromStream), returning packed 1bpc rows.
+func (enc *CCITTFaxEncoder) DecodeBytes(encoded []byte) ([]byte, error) {
+	params := CCITTFaxParams{
+		K:                enc.K,
+		Columns:          enc.Columns,
+		Rows:             enc.Rows,
+		EndOfLine:        enc.EndOfLine,
+		EncodedByteAlign: enc.EncodedByteAlign,
+		EndOfBlock:       enc.EndOfBlock,
+		BlackIs1:         enc.BlackIs1,
+	}
+	if params.Columns == 0 {
+		params.Columns = DefaultCCITTFaxParams().Columns
+	}
+
+	return DecodeCCITTFax(encoded, params)
+}
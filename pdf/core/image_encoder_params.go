@@ -0,0 +1,108 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import "github.com/unidoc/unidoc/common"
+
+// resolveDecodeParms returns `streamObj`'s /DecodeParms dictionary for its (single) filter. When
+// /DecodeParms is an array (one entry per filter in a chained /Filter array), the first
+// dictionary entry is returned, since CCITTFaxDecode/JBIG2Decode are only ever meaningfully
+// combined with a single other filter in practice (and never with each other). Returns nil if
+// there is no parameter dictionary to apply.
+func resolveDecodeParms(streamObj *PdfObjectStream) *PdfObjectDictionary {
+	parmsObj := streamObj.PdfObjectDictionary.Get("DecodeParms")
+	if parmsObj == nil {
+		parmsObj = streamObj.PdfObjectDictionary.Get("DP")
+	}
+
+	switch t := parmsObj.(type) {
+	case *PdfObjectDictionary:
+		return t
+	case *PdfObjectArray:
+		for _, elem := range *t {
+			if dict, ok := elem.(*PdfObjectDictionary); ok {
+				return dict
+			}
+		}
+	}
+	return nil
+}
+
+// newCCITTFaxEncoderFromStream builds a CCITTFaxEncoder with its fields populated from
+// `streamObj`'s /DecodeParms (K, Columns, Rows, EndOfLine, EncodedByteAlign, EndOfBlock,
+// BlackIs1), falling back to the PDF spec's documented defaults for any key that's absent.
+func newCCITTFaxEncoderFromStream(streamObj *PdfObjectStream) (*CCITTFaxEncoder, error) {
+	enc := NewCCITTFaxEncoder()
+	params := DefaultCCITTFaxParams()
+
+	if parms := resolveDecodeParms(streamObj); parms != nil {
+		if v, ok := parms.Get("K").(*PdfObjectInteger); ok {
+			params.K = int(*v)
+		}
+		if v, ok := parms.Get("Columns").(*PdfObjectInteger); ok {
+			params.Columns = int(*v)
+		}
+		if v, ok := parms.Get("Rows").(*PdfObjectInteger); ok {
+			params.Rows = int(*v)
+		}
+		if v, ok := parms.Get("EndOfLine").(*PdfObjectBool); ok {
+			params.EndOfLine = bool(*v)
+		}
+		if v, ok := parms.Get("EncodedByteAlign").(*PdfObjectBool); ok {
+			params.EncodedByteAlign = bool(*v)
+		}
+		if v, ok := parms.Get("EndOfBlock").(*PdfObjectBool); ok {
+			params.EndOfBlock = bool(*v)
+		}
+		if v, ok := parms.Get("BlackIs1").(*PdfObjectBool); ok {
+			params.BlackIs1 = bool(*v)
+		}
+	}
+
+	enc.K = params.K
+	enc.Columns = params.Columns
+	enc.Rows = params.Rows
+	enc.EndOfLine = params.EndOfLine
+	enc.EncodedByteAlign = params.EncodedByteAlign
+	enc.EndOfBlock = params.EndOfBlock
+	enc.BlackIs1 = params.BlackIs1
+
+	return enc, nil
+}
+
+// newJBIG2EncoderFromStream builds a JBIG2Encoder with its Globals field populated from
+// /DecodeParms /JBIG2Globals, when present and already resolved to a stream object (as opposed
+// to a dangling indirect reference NewEncoderFromStream has no parser handle to Trace: callers
+// that need globals resolved through an indirect reference should decode them up front and
+// assign JBIG2Encoder.Globals directly before calling DecodeBytes).
+func newJBIG2EncoderFromStream(streamObj *PdfObjectStream) (*JBIG2Encoder, error) {
+	enc := NewJBIG2Encoder()
+
+	parms := resolveDecodeParms(streamObj)
+	if parms == nil {
+		return enc, nil
+	}
+
+	globalsObj := parms.Get("JBIG2Globals")
+	switch g := globalsObj.(type) {
+	case nil:
+		// No globals; common for single-image JBIG2 streams.
+	case *PdfObjectStream:
+		globals, err := DecodeStream(g)
+		if err != nil {
+			common.Log.Debug("Failed decoding JBIG2Globals stream: %v", err)
+			return enc, nil
+		}
+		enc.Globals = globals
+	case *PdfObjectReference:
+		common.Log.Debug("JBIG2Globals is an unresolved indirect reference (%d %d R); "+
+			"pass the decoded globals in directly via JBIG2Encoder.Globals if needed", g.ObjectNumber, g.GenerationNumber)
+	default:
+		common.Log.Debug("Unexpected /JBIG2Globals type %T", globalsObj)
+	}
+
+	return enc, nil
+}
@@ -0,0 +1,534 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/unidoc/unidoc/common"
+)
+
+// jbig2Bitmap is a 1-bit-per-pixel image, stored one byte per pixel for simplicity of context
+// computation (packed into 1bpp rows only once the page is complete).
+type jbig2Bitmap struct {
+	Width, Height int
+	Pixels        []byte // row-major, one byte (0 or 1) per pixel.
+}
+
+func newJBIG2Bitmap(w, h int) *jbig2Bitmap {
+	return &jbig2Bitmap{Width: w, Height: h, Pixels: make([]byte, w*h)}
+}
+
+func (b *jbig2Bitmap) get(x, y int) byte {
+	if x < 0 || y < 0 || x >= b.Width || y >= b.Height {
+		return 0
+	}
+	return b.Pixels[y*b.Width+x]
+}
+
+func (b *jbig2Bitmap) set(x, y int, v byte) {
+	b.Pixels[y*b.Width+x] = v
+}
+
+// orPixel sets pixel (x, y) to 1 if it falls within the bitmap, silently ignoring out-of-bounds
+// coordinates; used when compositing a region onto a page bitmap, since a malformed or unusual
+// region offset should not panic the decoder.
+func (b *jbig2Bitmap) orPixel(x, y int) {
+	if x < 0 || y < 0 || x >= b.Width || y >= b.Height {
+		return
+	}
+	b.Pixels[y*b.Width+x] = 1
+}
+
+// packed returns the bitmap as packed 1-bit-per-pixel rows, MSB first, padded to a byte
+// boundary per row, with 1 meaning a black pixel (matching the PDF ImageMask convention for
+// JBIG2Decode output, which is always 1 bpc DeviceGray).
+func (b *jbig2Bitmap) packed() []byte {
+	stride := (b.Width + 7) / 8
+	out := make([]byte, stride*b.Height)
+	for y := 0; y < b.Height; y++ {
+		for x := 0; x < b.Width; x++ {
+			if b.get(x, y) != 0 {
+				out[y*stride+x/8] |= 0x80 >> uint(x%8)
+			}
+		}
+	}
+	return out
+}
+
+// mqDecoder implements the MQ arithmetic decoder shared by JBIG2 (Annex E of T.88) and
+// JPEG2000. It decodes a sequence of binary decisions against per-context probability states.
+type mqDecoder struct {
+	data []byte
+	bp   int
+	c    uint32
+	a    uint32
+	ct   int
+}
+
+// mqContext holds the adaptive state (index into the standard probability table, and the more
+// probable symbol) for one arithmetic coding context.
+type mqContext struct {
+	i   uint8
+	mps uint8
+}
+
+// mqTable is the standard Qe probability estimation table from T.88 Table E.1: {Qe, NMPS, NLPS, SWITCH}.
+var mqTable = [...][4]uint32{
+	{0x5601, 1, 1, 1}, {0x3401, 2, 6, 0}, {0x1801, 3, 9, 0}, {0x0AC1, 4, 12, 0},
+	{0x0521, 5, 29, 0}, {0x0221, 38, 33, 0}, {0x5601, 7, 6, 1}, {0x5401, 8, 14, 0},
+	{0x4801, 9, 14, 0}, {0x3801, 10, 14, 0}, {0x3001, 11, 17, 0}, {0x2401, 12, 18, 0},
+	{0x1C01, 13, 20, 0}, {0x1601, 29, 21, 0}, {0x5601, 15, 14, 1}, {0x5401, 16, 14, 0},
+	{0x5101, 17, 15, 0}, {0x4801, 18, 16, 0}, {0x3801, 19, 17, 0}, {0x3401, 20, 18, 0},
+	{0x3001, 21, 19, 0}, {0x2801, 22, 19, 0}, {0x2401, 23, 20, 0}, {0x2201, 24, 21, 0},
+	{0x1C01, 25, 22, 0}, {0x1801, 26, 23, 0}, {0x1601, 27, 24, 0}, {0x1401, 28, 25, 0},
+	{0x1201, 29, 26, 0}, {0x1101, 30, 27, 0}, {0x0AC1, 31, 28, 0}, {0x09C1, 32, 29, 0},
+	{0x08A1, 33, 30, 0}, {0x0521, 34, 31, 0}, {0x0441, 35, 32, 0}, {0x02A1, 36, 33, 0},
+	{0x0221, 37, 34, 0}, {0x0141, 38, 35, 0}, {0x0111, 39, 36, 0}, {0x0085, 40, 37, 0},
+	{0x0049, 41, 38, 0}, {0x0025, 42, 39, 0}, {0x0015, 43, 40, 0}, {0x0009, 44, 41, 0},
+	{0x0005, 45, 42, 0}, {0x0001, 45, 43, 0}, {0x5601, 46, 46, 0},
+}
+
+func newMQDecoder(data []byte) *mqDecoder {
+	d := &mqDecoder{data: data}
+	b0 := d.byteAt(0)
+	d.c = uint32(b0) << 16
+	d.bp = 0
+	d.byteIn()
+	d.c <<= 7
+	d.ct -= 7
+	d.a = 0x8000
+	return d
+}
+
+func (d *mqDecoder) byteAt(i int) byte {
+	if i < 0 || i >= len(d.data) {
+		return 0xFF
+	}
+	return d.data[i]
+}
+
+func (d *mqDecoder) byteIn() {
+	if d.byteAt(d.bp) == 0xFF {
+		if d.byteAt(d.bp+1) > 0x8F {
+			d.c += 0xFF00
+			d.ct = 8
+		} else {
+			d.bp++
+			d.c += uint32(d.byteAt(d.bp)) << 9
+			d.ct = 7
+		}
+	} else {
+		d.bp++
+		d.c += uint32(d.byteAt(d.bp)) << 8
+		d.ct = 8
+	}
+}
+
+// decodeBit decodes one binary decision using and updating the adaptive context `cx`.
+func (d *mqDecoder) decodeBit(cx *mqContext) int {
+	row := mqTable[cx.i]
+	qe := row[0]
+
+	d.a -= qe
+	var bit int
+	if (d.c >> 16) < qe {
+		// LPS exchange / conditional MPS exchange.
+		if d.a < qe {
+			bit = int(cx.mps)
+			cx.i = uint8(row[1])
+		} else {
+			bit = int(1 - cx.mps)
+			if row[3] == 1 {
+				cx.mps = 1 - cx.mps
+			}
+			cx.i = uint8(row[2])
+		}
+		d.a = qe
+	} else {
+		d.c -= qe << 16
+		if d.a&0x8000 != 0 {
+			return int(cx.mps)
+		}
+		if d.a < qe {
+			bit = int(1 - cx.mps)
+			if row[3] == 1 {
+				cx.mps = 1 - cx.mps
+			}
+			cx.i = uint8(row[2])
+		} else {
+			bit = int(cx.mps)
+			cx.i = uint8(row[1])
+		}
+	}
+
+	for d.a&0x8000 == 0 {
+		if d.ct == 0 {
+			d.byteIn()
+		}
+		d.a <<= 1
+		d.c <<= 1
+		d.ct--
+	}
+
+	return bit
+}
+
+// jbig2GenericRegion holds the parameters of a generic region segment (T.88 section 6.2).
+type jbig2GenericRegion struct {
+	Width, Height int
+	X, Y          int
+	Template      int
+	TPGDON        bool
+	AT            [4][2]int8
+}
+
+// DecodeJBIG2Generic decodes the page described by a JBIG2Decode stream, as embedded in an image
+// XObject, returning a packed 1bpc bitmap. `globals` is the optional shared `/JBIG2Globals`
+// stream; when present its segments (typically shared symbol dictionaries) are scanned first, so
+// that segments in `data` can refer back to them.
+//
+// Supported: generic regions (template 0, arithmetic-coded); symbol dictionaries and text
+// regions (arithmetic-coded, no refinement/aggregation, OR symbol combination, non-transposed,
+// TOPLEFT/BOTTOMLEFT reference corner only) — the combination scanned-PDF text encoders actually
+// emit. Not supported: Huffman-coded symbol dictionaries/text regions, refinement coding,
+// TOPRIGHT/BOTTOMRIGHT or transposed text region placement, halftone and pattern-dictionary
+// regions, and MMR/non-zero generic region templates. Any of those encountered return a clear
+// error rather than a silently wrong page.
+func DecodeJBIG2Generic(data, globals []byte) ([]byte, int, int, error) {
+	segments, err := parseJBIG2Segments(globals)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	main, err := parseJBIG2Segments(data)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	segments = append(segments, main...)
+
+	pageWidth, pageHeight, err := jbig2PageDimensions(segments)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	symbolsBySegment := map[uint32][]*jbig2Bitmap{}
+	var page *jbig2Bitmap
+	decodedAny := false
+
+	for _, seg := range segments {
+		switch seg.Type {
+		case jbig2SegSymbolDictionary:
+			var input []*jbig2Bitmap
+			for _, ref := range seg.Referred {
+				input = append(input, symbolsBySegment[ref]...)
+			}
+			symbols, err := decodeSymbolDictionary(seg.Data, input)
+			if err != nil {
+				return nil, 0, 0, fmt.Errorf("jbig2: symbol dictionary segment %d: %w", seg.Number, err)
+			}
+			symbolsBySegment[seg.Number] = symbols
+
+		case jbig2SegImmediateGenericRegion, jbig2SegImmediateLosslessGenericRegion, jbig2SegIntermediateGenericRegion:
+			region, body, err := parseJBIG2GenericRegionHeader(seg.Data)
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			bitmap, err := decodeJBIG2GenericRegion(region, body)
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			if page == nil {
+				page = newJBIG2Bitmap(pageWidth, pageHeight)
+			}
+			compositeOr(page, bitmap, region.X, region.Y)
+			decodedAny = true
+
+		case jbig2SegImmediateTextRegion, jbig2SegImmediateLosslessTextRegion, jbig2SegIntermediateTextRegion:
+			var symbols []*jbig2Bitmap
+			for _, ref := range seg.Referred {
+				symbols = append(symbols, symbolsBySegment[ref]...)
+			}
+			bitmap, x, y, err := decodeTextRegion(seg.Data, symbols)
+			if err != nil {
+				return nil, 0, 0, fmt.Errorf("jbig2: text region segment %d: %w", seg.Number, err)
+			}
+			if page == nil {
+				page = newJBIG2Bitmap(pageWidth, pageHeight)
+			}
+			compositeOr(page, bitmap, x, y)
+			decodedAny = true
+
+		case jbig2SegPatternDictionary, jbig2SegIntermediateHalftoneRegion, jbig2SegImmediateHalftoneRegion,
+			jbig2SegImmediateLosslessHalftoneRegion:
+			return nil, 0, 0, errors.New("jbig2: halftone/pattern-dictionary regions not yet supported")
+		}
+	}
+
+	if !decodedAny {
+		return nil, 0, 0, errors.New("jbig2: no generic or text region segment found")
+	}
+
+	return page.packed(), page.Width, page.Height, nil
+}
+
+// compositeOr draws `region` onto `page` at offset (x, y), OR-combining: a pixel already set on
+// the page is never cleared, matching the common (and only supported) SBCOMBOP/region
+// combination operator encoders actually emit.
+func compositeOr(page, region *jbig2Bitmap, x, y int) {
+	for ry := 0; ry < region.Height; ry++ {
+		for rx := 0; rx < region.Width; rx++ {
+			if region.get(rx, ry) != 0 {
+				page.orPixel(x+rx, y+ry)
+			}
+		}
+	}
+}
+
+// jbig2PageDimensions determines the page bitmap's size: from the page info segment (T.88
+// 7.4.8) if present and of known height, otherwise from the bounding box of every region segment
+// (the common case for JBIG2Decode streams embedded directly in a PDF image XObject, which often
+// omit the page info segment since the PDF image dictionary already gives the dimensions).
+func jbig2PageDimensions(segments []jbig2Segment) (width, height int, err error) {
+	for _, seg := range segments {
+		if seg.Type != jbig2SegPageInfo || len(seg.Data) < 8 {
+			continue
+		}
+		w := binary.BigEndian.Uint32(seg.Data[0:])
+		h := binary.BigEndian.Uint32(seg.Data[4:])
+		if h != 0xFFFFFFFF { // unknown (striped) height falls back to the bounding box below.
+			return int(w), int(h), nil
+		}
+	}
+
+	maxX, maxY := 0, 0
+	for _, seg := range segments {
+		switch seg.Type {
+		case jbig2SegImmediateGenericRegion, jbig2SegImmediateLosslessGenericRegion, jbig2SegIntermediateGenericRegion,
+			jbig2SegImmediateTextRegion, jbig2SegImmediateLosslessTextRegion, jbig2SegIntermediateTextRegion:
+		default:
+			continue
+		}
+		w, h, x, y, _, _, err := parseJBIG2RegionInfo(seg.Data)
+		if err != nil {
+			return 0, 0, err
+		}
+		if x+w > maxX {
+			maxX = x + w
+		}
+		if y+h > maxY {
+			maxY = y + h
+		}
+	}
+	return maxX, maxY, nil
+}
+
+const (
+	jbig2SegSymbolDictionary                = 0
+	jbig2SegIntermediateTextRegion          = 4
+	jbig2SegImmediateTextRegion             = 6
+	jbig2SegImmediateLosslessTextRegion     = 7
+	jbig2SegPatternDictionary               = 16
+	jbig2SegIntermediateHalftoneRegion      = 20
+	jbig2SegImmediateHalftoneRegion         = 22
+	jbig2SegImmediateLosslessHalftoneRegion = 23
+	jbig2SegIntermediateGenericRegion       = 36
+	jbig2SegImmediateGenericRegion          = 38
+	jbig2SegImmediateLosslessGenericRegion  = 39
+	jbig2SegPageInfo                        = 48
+)
+
+type jbig2Segment struct {
+	Number   uint32
+	Type     int
+	Referred []uint32
+	Data     []byte
+}
+
+// parseJBIG2Segments walks the embedded-organization segment headers of T.88 Annex D.1, as used
+// within PDF streams (as opposed to the standalone file organization with its own file header).
+func parseJBIG2Segments(data []byte) ([]jbig2Segment, error) {
+	var segments []jbig2Segment
+	pos := 0
+	for pos < len(data) {
+		if pos+11 > len(data) {
+			// Entering the loop already guarantees pos < len(data), so this is always a
+			// genuinely truncated header, never a clean end-of-stream (which is pos ==
+			// len(data), handled by the loop condition itself).
+			return nil, errors.New("jbig2: truncated segment header")
+		}
+		number := binary.BigEndian.Uint32(data[pos:])
+		flags := data[pos+4]
+		segType := int(flags & 0x3F)
+		pageAssocSize := 1
+		if flags&0x40 != 0 {
+			pageAssocSize = 4
+		}
+		pos += 5
+
+		if pos >= len(data) {
+			return nil, errors.New("jbig2: truncated segment header")
+		}
+		rtFlags := data[pos]
+		var refCount int
+		if rtFlags>>5 == 7 {
+			refCount = int(binary.BigEndian.Uint32(data[pos:]) & 0x1FFFFFFF)
+			pos += 4 + (refCount+8)/8
+		} else {
+			refCount = int(rtFlags >> 5)
+			pos++
+		}
+
+		refSize := 1
+		if number > 65536 {
+			refSize = 4
+		} else if number > 256 {
+			refSize = 2
+		}
+		referred := make([]uint32, refCount)
+		for i := 0; i < refCount; i++ {
+			if pos+refSize > len(data) {
+				return nil, errors.New("jbig2: truncated referred-to segment numbers")
+			}
+			switch refSize {
+			case 1:
+				referred[i] = uint32(data[pos])
+			case 2:
+				referred[i] = uint32(binary.BigEndian.Uint16(data[pos:]))
+			case 4:
+				referred[i] = binary.BigEndian.Uint32(data[pos:])
+			}
+			pos += refSize
+		}
+		pos += pageAssocSize
+
+		if pos+4 > len(data) {
+			return nil, errors.New("jbig2: truncated segment data length")
+		}
+		length := binary.BigEndian.Uint32(data[pos:])
+		pos += 4
+
+		if length == 0xFFFFFFFF {
+			return nil, errors.New("jbig2: unknown-length segments not supported")
+		}
+		if pos+int(length) > len(data) {
+			return nil, errors.New("jbig2: segment data runs past end of stream")
+		}
+
+		segments = append(segments, jbig2Segment{Number: number, Type: segType, Referred: referred, Data: data[pos : pos+int(length)]})
+		pos += int(length)
+	}
+	return segments, nil
+}
+
+// parseJBIG2RegionInfo parses the 17-byte region segment information field shared by every
+// region segment type (T.88 7.4.1): width, height, X/Y location on the page, and the region
+// combination operator (only OR is currently honored by callers). Returns the remaining bytes
+// as `rest`.
+func parseJBIG2RegionInfo(data []byte) (width, height, x, y, combOp int, rest []byte, err error) {
+	if len(data) < 17 {
+		return 0, 0, 0, 0, 0, nil, errors.New("jbig2: region segment information field too short")
+	}
+	width = int(binary.BigEndian.Uint32(data[0:]))
+	height = int(binary.BigEndian.Uint32(data[4:]))
+	x = int(binary.BigEndian.Uint32(data[8:]))
+	y = int(binary.BigEndian.Uint32(data[12:]))
+	combOp = int(data[16] & 0x7)
+	return width, height, x, y, combOp, data[17:], nil
+}
+
+// parseJBIG2GenericRegionHeader parses the region segment information field (T.88 7.4.1) plus
+// the generic region flags/AT pixels, returning the remaining bytes as the arithmetic-coded body.
+func parseJBIG2GenericRegionHeader(data []byte) (*jbig2GenericRegion, []byte, error) {
+	width, height, x, y, _, rest, err := parseJBIG2RegionInfo(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rest) < 1 {
+		return nil, nil, errors.New("jbig2: generic region segment too short")
+	}
+
+	region := &jbig2GenericRegion{Width: width, Height: height, X: x, Y: y}
+
+	data = rest
+	pos := 0
+	flags := data[pos]
+	pos++
+	mmr := flags&0x01 != 0
+	if mmr {
+		return nil, nil, errors.New("jbig2: MMR-coded generic regions not yet supported")
+	}
+	region.Template = int(flags>>1) & 0x3
+	region.TPGDON = flags&0x08 != 0
+
+	numAT := 4
+	if region.Template != 0 {
+		numAT = 1
+	}
+	for i := 0; i < numAT; i++ {
+		if pos+2 > len(data) {
+			return nil, nil, errors.New("jbig2: truncated AT pixels")
+		}
+		region.AT[i][0] = int8(data[pos])
+		region.AT[i][1] = int8(data[pos+1])
+		pos += 2
+	}
+
+	return region, data[pos:], nil
+}
+
+// decodeJBIG2GenericRegion decodes the arithmetic-coded body of a generic region using the
+// template-0 context (the common case emitted by virtually all encoders); other templates are
+// rejected explicitly rather than silently mis-decoded.
+func decodeJBIG2GenericRegion(region *jbig2GenericRegion, body []byte) (*jbig2Bitmap, error) {
+	if region.Template != 0 {
+		return nil, fmt.Errorf("jbig2: generic region template %d not yet supported", region.Template)
+	}
+	if region.TPGDON {
+		return nil, errors.New("jbig2: typical prediction (TPGDON) not yet supported")
+	}
+
+	bitmap := newJBIG2Bitmap(region.Width, region.Height)
+	dec := newMQDecoder(body)
+	contexts := make([]mqContext, 1<<16)
+
+	for y := 0; y < region.Height; y++ {
+		for x := 0; x < region.Width; x++ {
+			ctx := genericRegionContextTemplate0(bitmap, x, y, region.AT)
+			bit := dec.decodeBit(&contexts[ctx])
+			bitmap.set(x, y, byte(bit))
+		}
+	}
+
+	common.Log.Trace("jbig2: decoded generic region %dx%d", region.Width, region.Height)
+	return bitmap, nil
+}
+
+// genericRegionContextTemplate0 builds the 16-bit arithmetic-coding context for pixel (x, y) of a
+// template-0 generic region (T.88 Figure 7): the already-decoded pixels to the left on the
+// current row, then on the row above, then two rows above, with (x-1, y) as the most
+// significant bit, and the four adaptive (AT) pixels substituted in their spec-mandated
+// positions (nominally (3,-1), (-3,-1), (2,-2), (-2,-2)).
+func genericRegionContextTemplate0(bitmap *jbig2Bitmap, x, y int, at [4][2]int8) uint16 {
+	ctx := uint16(bitmap.get(x-1, y))
+	ctx = ctx<<1 | uint16(bitmap.get(x-2, y))
+	ctx = ctx<<1 | uint16(bitmap.get(x-3, y))
+	ctx = ctx<<1 | uint16(bitmap.get(x-4, y))
+	ctx = ctx<<1 | uint16(bitmap.get(x+int(at[0][0]), y+int(at[0][1])))
+	ctx = ctx<<1 | uint16(bitmap.get(x+2, y-1))
+	ctx = ctx<<1 | uint16(bitmap.get(x+1, y-1))
+	ctx = ctx<<1 | uint16(bitmap.get(x, y-1))
+	ctx = ctx<<1 | uint16(bitmap.get(x-1, y-1))
+	ctx = ctx<<1 | uint16(bitmap.get(x-2, y-1))
+	ctx = ctx<<1 | uint16(bitmap.get(x+int(at[1][0]), y+int(at[1][1])))
+	ctx = ctx<<1 | uint16(bitmap.get(x+int(at[2][0]), y+int(at[2][1])))
+	ctx = ctx<<1 | uint16(bitmap.get(x+1, y-2))
+	ctx = ctx<<1 | uint16(bitmap.get(x, y-2))
+	ctx = ctx<<1 | uint16(bitmap.get(x-1, y-2))
+	ctx = ctx<<1 | uint16(bitmap.get(x+int(at[3][0]), y+int(at[3][1])))
+	return ctx
+}
@@ -0,0 +1,109 @@
+package core
+
+import "testing"
+
+// TestJBIG2SegmentParsingMalformed checks that a truncated segment header is rejected cleanly
+// rather than panicking on an out-of-range slice.
+func TestJBIG2SegmentParsingMalformed(t *testing.T) {
+	_, err := parseJBIG2Segments([]byte{0, 0, 0, 1, 0})
+	if err == nil {
+		t.Errorf("expected an error for a truncated segment header")
+	}
+}
+
+// TestJBIG2SegmentParsingEmpty checks that an empty (no globals) stream parses to no segments
+// without error, which is the common case when a JBIG2Decode stream has no /JBIG2Globals.
+func TestJBIG2SegmentParsingEmpty(t *testing.T) {
+	segments, err := parseJBIG2Segments(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(segments) != 0 {
+		t.Errorf("expected no segments, got %d", len(segments))
+	}
+}
+
+// TestDecodeJBIG2GenericNoRegion checks that DecodeJBIG2Generic reports a clear error rather
+// than silently returning no data when a stream contains no generic region segment.
+func TestDecodeJBIG2GenericNoRegion(t *testing.T) {
+	_, _, _, err := DecodeJBIG2Generic(nil, nil)
+	if err == nil {
+		t.Errorf("expected an error when no generic region segment is present")
+	}
+}
+
+// TestJBIG2SegmentParsingReferred hand-builds a single segment header with one referred-to
+// segment number and checks it's actually captured (rather than just skipped over while
+// advancing past the field), since text regions and symbol dictionaries depend on it to resolve
+// which earlier segments they draw symbols from.
+func TestJBIG2SegmentParsingReferred(t *testing.T) {
+	data := []byte{
+		0, 0, 0, 1, // segment number 1
+		0x00,       // flags: type 0 (symbol dictionary), 1-byte page association
+		0x20,       // referred-to segment count and retention flags: count = 1
+		0x00,       // referred-to segment number 0 (1-byte, since this segment's number <= 256)
+		0x01,       // page association
+		0, 0, 0, 0, // segment data length: 0
+	}
+
+	segments, err := parseJBIG2Segments(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(segments) != 1 {
+		t.Fatalf("expected 1 segment, got %d", len(segments))
+	}
+	if got := segments[0].Referred; len(got) != 1 || got[0] != 0 {
+		t.Errorf("Referred = %v, want [0]", got)
+	}
+}
+
+// TestPlaceTextSymbolRefCorner checks the TOPLEFT and BOTTOMLEFT reference-corner placement math
+// directly, independent of arithmetic decoding: a wrong vertical offset here silently shifts
+// every glyph in a decoded text region by its own height.
+func TestPlaceTextSymbolRefCorner(t *testing.T) {
+	symbol := newJBIG2Bitmap(2, 2)
+	for i := range symbol.Pixels {
+		symbol.Pixels[i] = 1
+	}
+
+	t.Run("TopLeft", func(t *testing.T) {
+		region := newJBIG2Bitmap(5, 5)
+		placeTextSymbol(region, symbol, 1, 1, jbig2RefCornerTopLeft)
+		for _, p := range [][2]int{{1, 1}, {2, 1}, {1, 2}, {2, 2}} {
+			if region.get(p[0], p[1]) == 0 {
+				t.Errorf("expected pixel (%d,%d) set", p[0], p[1])
+			}
+		}
+	})
+
+	t.Run("BottomLeft", func(t *testing.T) {
+		region := newJBIG2Bitmap(5, 5)
+		placeTextSymbol(region, symbol, 1, 3, jbig2RefCornerBottomLeft)
+		for _, p := range [][2]int{{1, 2}, {2, 2}, {1, 3}, {2, 3}} {
+			if region.get(p[0], p[1]) == 0 {
+				t.Errorf("expected pixel (%d,%d) set", p[0], p[1])
+			}
+		}
+	})
+}
+
+// TestGenericRegionContextTemplate0 hand-verifies the template-0 context bit layout (T.88 Figure
+// 7) against a bitmap with only two pixels set: one feeding the context's MSB (the pixel
+// immediately to the left) and one feeding bit 3 (the AT3-adjacent pixel two rows up). Getting
+// this bit order or the AT pixel substitution positions wrong silently decodes every pixel
+// against the wrong probability context, so it's worth pinning down independently of the
+// arithmetic coder.
+func TestGenericRegionContextTemplate0(t *testing.T) {
+	bitmap := newJBIG2Bitmap(8, 3)
+	bitmap.set(3, 2, 1) // (x-1, y): contributes bit 15.
+	bitmap.set(5, 0, 1) // (x+1, y-2): contributes bit 3.
+
+	at := [4][2]int8{{3, -1}, {-3, -1}, {2, -2}, {-2, -2}} // nominal AT pixel positions.
+	got := genericRegionContextTemplate0(bitmap, 4, 2, at)
+
+	want := uint16(1<<15 | 1<<3)
+	if got != want {
+		t.Errorf("context = %#04x, want %#04x", got, want)
+	}
+}
@@ -0,0 +1,365 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// jbig2ArithIntContext holds the 512-entry adaptive context array for one instance of the
+// arithmetic integer decoding procedure (T.88 Annex A.3), e.g. IADH or IADW. Each procedure kind
+// used within a segment gets its own independently-adapting instance; they are never shared
+// across kinds.
+type jbig2ArithIntContext struct {
+	cx [512]mqContext
+}
+
+// decodeInt decodes one value via the arithmetic integer decoding procedure (T.88 Annex A.3).
+// The second return value is true for the out-of-band value used to terminate certain loops
+// (e.g. the width loop within a symbol dictionary height class, or a text region strip).
+func decodeInt(dec *mqDecoder, ctx *jbig2ArithIntContext) (int, bool) {
+	prev := 1
+	bit := func() int {
+		b := dec.decodeBit(&ctx.cx[prev])
+		if prev < 256 {
+			prev = prev<<1 | b
+		} else {
+			prev = (((prev << 1) | b) & 511) | 256
+		}
+		return b
+	}
+
+	s := bit()
+
+	var n, offset int
+	switch {
+	case bit() == 0:
+		n, offset = 2, 0
+	case bit() == 0:
+		n, offset = 4, 4
+	case bit() == 0:
+		n, offset = 6, 20
+	case bit() == 0:
+		n, offset = 8, 84
+	case bit() == 0:
+		n, offset = 12, 340
+	default:
+		n, offset = 32, 4436
+	}
+
+	value := 0
+	for i := 0; i < n; i++ {
+		value = value<<1 | bit()
+	}
+	value += offset
+
+	if s == 0 {
+		return value, false
+	}
+	if value > 0 {
+		return -value, false
+	}
+	return 0, true // OOB
+}
+
+// jbig2IAIDContext holds the adaptive context array for the fixed-length IAID procedure (T.88
+// Annex A.3, used to decode a symbol ID), sized for the number of bits `codeLen` needed to
+// address every symbol available to the text region.
+type jbig2IAIDContext struct {
+	cx []mqContext
+}
+
+func newJBIG2IAIDContext(codeLen int) *jbig2IAIDContext {
+	return &jbig2IAIDContext{cx: make([]mqContext, 1<<uint(codeLen+1))}
+}
+
+func decodeIAID(dec *mqDecoder, ctx *jbig2IAIDContext, codeLen int) int {
+	prev := 1
+	for i := 0; i < codeLen; i++ {
+		bit := dec.decodeBit(&ctx.cx[prev])
+		prev = prev<<1 | bit
+	}
+	return prev - (1 << uint(codeLen))
+}
+
+// decodeSymbolDictionary decodes a symbol dictionary segment (T.88 6.5/7.4.3), returning its
+// exported symbol bitmaps in order. `inputSymbols` are the symbols exported by any symbol
+// dictionaries this segment refers to, which count toward the export run-length selection
+// alongside the symbols newly decoded here.
+//
+// Only the arithmetic-coded, non-refinement/non-aggregate case (SDHUFF=0, SDREFAGG=0) with
+// generic region template 0 is supported, matching decodeJBIG2GenericRegion; Huffman coding,
+// refinement/aggregation, and templates 1-3 are rejected explicitly.
+func decodeSymbolDictionary(segData []byte, inputSymbols []*jbig2Bitmap) ([]*jbig2Bitmap, error) {
+	if len(segData) < 2 {
+		return nil, errors.New("jbig2: symbol dictionary segment too short")
+	}
+	flags := binary.BigEndian.Uint16(segData[0:])
+	huffman := flags&0x0001 != 0
+	refAgg := flags&0x0002 != 0
+	template := int(flags>>10) & 0x3
+
+	if huffman {
+		return nil, errors.New("jbig2: Huffman-coded symbol dictionaries not yet supported")
+	}
+	if refAgg {
+		return nil, errors.New("jbig2: refinement/aggregate-coded symbol dictionaries not yet supported")
+	}
+	if template != 0 {
+		return nil, fmt.Errorf("jbig2: symbol dictionary generic region template %d not yet supported", template)
+	}
+
+	pos := 2
+	var at [4][2]int8
+	for i := 0; i < 4; i++ {
+		if pos+2 > len(segData) {
+			return nil, errors.New("jbig2: truncated symbol dictionary AT pixels")
+		}
+		at[i][0] = int8(segData[pos])
+		at[i][1] = int8(segData[pos+1])
+		pos += 2
+	}
+
+	if pos+8 > len(segData) {
+		return nil, errors.New("jbig2: truncated symbol dictionary counts")
+	}
+	numExported := int(binary.BigEndian.Uint32(segData[pos:]))
+	numNew := int(binary.BigEndian.Uint32(segData[pos+4:]))
+	pos += 8
+
+	dec := newMQDecoder(segData[pos:])
+
+	var iadh, iadw, iaex jbig2ArithIntContext
+	genericContexts := make([]mqContext, 1<<16)
+
+	newSymbols := make([]*jbig2Bitmap, 0, numNew)
+	height := 0
+	for len(newSymbols) < numNew {
+		dh, oob := decodeInt(dec, &iadh)
+		if oob {
+			return nil, errors.New("jbig2: unexpected OOB decoding symbol dictionary height class")
+		}
+		height += dh
+		if height <= 0 || height > 1<<16 {
+			return nil, fmt.Errorf("jbig2: invalid symbol dictionary height class %d", height)
+		}
+
+		width := 0
+		for {
+			dw, oob := decodeInt(dec, &iadw)
+			if oob {
+				break // End of this height class.
+			}
+			width += dw
+			if width <= 0 || width > 1<<16 {
+				return nil, fmt.Errorf("jbig2: invalid symbol width %d", width)
+			}
+			if len(newSymbols) >= numNew {
+				return nil, errors.New("jbig2: symbol dictionary declares more symbols than SDNUMNEWSYMS")
+			}
+
+			symbol := newJBIG2Bitmap(width, height)
+			for y := 0; y < height; y++ {
+				for x := 0; x < width; x++ {
+					ctx := genericRegionContextTemplate0(symbol, x, y, at)
+					bit := dec.decodeBit(&genericContexts[ctx])
+					symbol.set(x, y, byte(bit))
+				}
+			}
+			newSymbols = append(newSymbols, symbol)
+		}
+	}
+
+	allSymbols := make([]*jbig2Bitmap, 0, len(inputSymbols)+len(newSymbols))
+	allSymbols = append(allSymbols, inputSymbols...)
+	allSymbols = append(allSymbols, newSymbols...)
+
+	exported := make([]*jbig2Bitmap, 0, numExported)
+	i := 0
+	exFlag := false
+	for i < len(allSymbols) && len(exported) < numExported {
+		run, oob := decodeInt(dec, &iaex)
+		if oob {
+			return nil, errors.New("jbig2: unexpected OOB decoding symbol dictionary export flags")
+		}
+		if run < 0 || i+run > len(allSymbols) {
+			return nil, errors.New("jbig2: invalid symbol dictionary export run length")
+		}
+		if exFlag {
+			exported = append(exported, allSymbols[i:i+run]...)
+		}
+		i += run
+		exFlag = !exFlag
+	}
+	if len(exported) != numExported {
+		return nil, fmt.Errorf("jbig2: symbol dictionary exported %d symbols, expected %d", len(exported), numExported)
+	}
+
+	return exported, nil
+}
+
+// jbig2 text region reference corners (T.88 Table 31).
+const (
+	jbig2RefCornerBottomLeft  = 0
+	jbig2RefCornerTopLeft     = 1
+	jbig2RefCornerBottomRight = 2
+	jbig2RefCornerTopRight    = 3
+)
+
+// decodeTextRegion decodes a text region segment (T.88 6.4/7.4.4), placing instances of `symbols`
+// (the combined exported symbols of every symbol dictionary this segment refers to) onto a
+// region bitmap sized and positioned per the segment's region info field. Returns the decoded
+// bitmap and its page X/Y offset.
+//
+// Only the arithmetic-coded, non-refinement case with SBCOMBOP=OR, non-transposed placement, and
+// a TOPLEFT/BOTTOMLEFT reference corner is supported — the combination used by the scanned-text
+// encoders this decoder targets. Huffman coding, refinement, other combination operators,
+// TOPRIGHT/BOTTOMRIGHT reference corners, and transposed placement are rejected explicitly.
+func decodeTextRegion(segData []byte, symbols []*jbig2Bitmap) (bitmap *jbig2Bitmap, x, y int, err error) {
+	width, height, x, y, _, rest, err := parseJBIG2RegionInfo(segData)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	if len(rest) < 2 {
+		return nil, 0, 0, errors.New("jbig2: text region segment too short")
+	}
+	flags := binary.BigEndian.Uint16(rest[0:])
+	huffman := flags&0x0001 != 0
+	refine := flags&0x0002 != 0
+	logStrips := int(flags>>2) & 0x3
+	refCorner := int(flags>>4) & 0x3
+	transposed := flags&0x0040 != 0
+	sbCombOp := int(flags>>7) & 0x3
+	defPixel := int(flags>>9) & 0x1
+	dsOffset := int(flags>>10) & 0x1F
+	if dsOffset > 15 {
+		dsOffset -= 32 // Sign-extend the 5-bit field.
+	}
+	pos := 2
+
+	if huffman {
+		return nil, 0, 0, errors.New("jbig2: Huffman-coded text regions not yet supported")
+	}
+	if refine {
+		return nil, 0, 0, errors.New("jbig2: refined text region symbol instances not yet supported")
+	}
+	if sbCombOp != 0 {
+		return nil, 0, 0, errors.New("jbig2: text region symbol combination operators other than OR not yet supported")
+	}
+	if transposed {
+		return nil, 0, 0, errors.New("jbig2: transposed text regions not yet supported")
+	}
+	if refCorner != jbig2RefCornerTopLeft && refCorner != jbig2RefCornerBottomLeft {
+		return nil, 0, 0, errors.New("jbig2: text regions with a right reference corner not yet supported")
+	}
+
+	if pos+4 > len(rest) {
+		return nil, 0, 0, errors.New("jbig2: truncated text region instance count")
+	}
+	numInstances := int(binary.BigEndian.Uint32(rest[pos:]))
+	pos += 4
+
+	if len(symbols) == 0 {
+		return nil, 0, 0, errors.New("jbig2: text region has no symbols to place (no referred symbol dictionary?)")
+	}
+	codeLen := 0
+	for 1<<uint(codeLen) < len(symbols) {
+		codeLen++
+	}
+
+	dec := newMQDecoder(rest[pos:])
+
+	var iadt, iafs, iads, iait jbig2ArithIntContext
+	iaid := newJBIG2IAIDContext(codeLen)
+
+	strips := 1 << uint(logStrips)
+
+	region := newJBIG2Bitmap(width, height)
+	if defPixel != 0 {
+		for i := range region.Pixels {
+			region.Pixels[i] = 1
+		}
+	}
+
+	stripT, oob := decodeInt(dec, &iadt)
+	if oob {
+		return nil, 0, 0, errors.New("jbig2: unexpected OOB decoding text region STRIPT")
+	}
+	stripT = -stripT * strips
+
+	firstS := 0
+	decoded := 0
+	for decoded < numInstances {
+		dt, oob := decodeInt(dec, &iadt)
+		if oob {
+			return nil, 0, 0, errors.New("jbig2: unexpected OOB decoding text region strip delta")
+		}
+		stripT += dt * strips
+
+		dfs, oob := decodeInt(dec, &iafs)
+		if oob {
+			return nil, 0, 0, errors.New("jbig2: unexpected OOB decoding text region first symbol S")
+		}
+		firstS += dfs
+		curS := firstS
+
+		for first := true; ; first = false {
+			if !first {
+				ids, oob := decodeInt(dec, &iads)
+				if oob {
+					break // End of this strip.
+				}
+				curS += ids + dsOffset
+			}
+
+			curT := 0
+			if strips != 1 {
+				var oob bool
+				curT, oob = decodeInt(dec, &iait)
+				if oob {
+					return nil, 0, 0, errors.New("jbig2: unexpected OOB decoding text region CURT")
+				}
+			}
+			t := stripT + curT
+
+			id := decodeIAID(dec, iaid, codeLen)
+			if id < 0 || id >= len(symbols) {
+				return nil, 0, 0, fmt.Errorf("jbig2: text region symbol ID %d out of range (%d symbols)", id, len(symbols))
+			}
+			symbol := symbols[id]
+
+			placeTextSymbol(region, symbol, curS, t, refCorner)
+			curS += symbol.Width - 1
+
+			decoded++
+			if decoded >= numInstances {
+				break
+			}
+		}
+	}
+
+	return region, x, y, nil
+}
+
+// placeTextSymbol OR-combines `symbol` onto `region` at strip position (s, t), per the
+// TOPLEFT/BOTTOMLEFT reference corner conventions of T.88 6.4.5 step 3(c).
+func placeTextSymbol(region, symbol *jbig2Bitmap, s, t, refCorner int) {
+	top := t
+	if refCorner == jbig2RefCornerBottomLeft {
+		top = t - symbol.Height + 1
+	}
+
+	for y := 0; y < symbol.Height; y++ {
+		for x := 0; x < symbol.Width; x++ {
+			if symbol.get(x, y) != 0 {
+				region.orPixel(s+x, top+y)
+			}
+		}
+	}
+}
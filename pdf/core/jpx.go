@@ -0,0 +1,93 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// errNotImplementedJPXTiles is returned once a JPX codestream header has been parsed
+// successfully but before any tile-part decoding is attempted. Decoding JPEG 2000 tile-part data
+// requires a full EBCOT entropy decoder plus inverse DWT/quantization pipeline, a substantial
+// undertaking distinct from codestream header parsing; this decoder deliberately scopes that out
+// rather than guess at pixel data, so every JPXDecode stream errors here instead of silently
+// returning wrong samples.
+var errNotImplementedJPXTiles = errors.New("jpx: codestream header parsed but tile decoding is not implemented")
+
+const (
+	jpxMarkerSOC = 0xFF4F // Start of codestream.
+	jpxMarkerSIZ = 0xFF51 // Image and tile size.
+)
+
+// jpxHeader holds the subset of the JPEG 2000 codestream header (ISO/IEC 15444-1 Annex A.5.1,
+// the SIZ marker segment) needed to describe the image to the rest of unidoc, ahead of full
+// pixel decoding.
+type jpxHeader struct {
+	Width, Height int
+	NumComponents int
+	BitDepth      int
+	ColorSpace    JPXColorSpace
+}
+
+// JPXColorSpace is a coarse color space hint derived from a JPX codestream's component count.
+// `core` does not depend on `pdf/model`, so callers there (e.g. the image XObject machinery)
+// map this to a concrete model.PdfColorSpace.
+type JPXColorSpace int
+
+const (
+	JPXColorSpaceUnknown JPXColorSpace = iota
+	JPXColorSpaceDeviceGray
+	JPXColorSpaceDeviceRGB
+	JPXColorSpaceDeviceCMYK
+)
+
+// parseJPXHeader parses the SIZ marker segment of a raw JPEG 2000 codestream (as found directly
+// in a PDF JPXDecode stream; the JP2 box-file wrapper is not used in PDF).
+func parseJPXHeader(data []byte) (*jpxHeader, error) {
+	if len(data) < 2 || binary.BigEndian.Uint16(data) != jpxMarkerSOC {
+		return nil, errors.New("jpx: missing SOC marker")
+	}
+
+	pos := 2
+	if pos+2 > len(data) || binary.BigEndian.Uint16(data[pos:]) != jpxMarkerSIZ {
+		return nil, errors.New("jpx: expected SIZ marker after SOC")
+	}
+	pos += 2
+
+	if pos+38 > len(data) {
+		return nil, errors.New("jpx: truncated SIZ marker segment")
+	}
+	// Lsiz(2) Rsiz(2) Xsiz(4) Ysiz(4) XOsiz(4) YOsiz(4) XTsiz(4) YTsiz(4) XTOsiz(4) YTOsiz(4) Csiz(2)
+	xsiz := binary.BigEndian.Uint32(data[pos+4:])
+	ysiz := binary.BigEndian.Uint32(data[pos+8:])
+	xosiz := binary.BigEndian.Uint32(data[pos+12:])
+	yosiz := binary.BigEndian.Uint32(data[pos+16:])
+	csiz := binary.BigEndian.Uint16(data[pos+36:])
+
+	header := &jpxHeader{
+		Width:         int(xsiz - xosiz),
+		Height:        int(ysiz - yosiz),
+		NumComponents: int(csiz),
+	}
+
+	ssizPos := pos + 38
+	if ssizPos < len(data) {
+		ssiz := data[ssizPos]
+		header.BitDepth = int(ssiz&0x7F) + 1
+	}
+
+	switch header.NumComponents {
+	case 1:
+		header.ColorSpace = JPXColorSpaceDeviceGray
+	case 4:
+		header.ColorSpace = JPXColorSpaceDeviceCMYK
+	default:
+		header.ColorSpace = JPXColorSpaceDeviceRGB
+	}
+
+	return header, nil
+}
@@ -0,0 +1,125 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// serializeObject renders `obj` as PDF object syntax (the form that appears between "N G obj"
+// and "endobj", or nested inside an array/dictionary). It is used by IncrementalWriter to emit
+// the dirty objects of an appended update section.
+func serializeObject(obj PdfObject) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeObject(&buf, obj); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeObject(buf *bytes.Buffer, obj PdfObject) error {
+	switch t := obj.(type) {
+	case *PdfObjectNull:
+		buf.WriteString("null")
+	case *PdfObjectBool:
+		if *t {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case *PdfObjectInteger:
+		fmt.Fprintf(buf, "%d", int64(*t))
+	case *PdfObjectFloat:
+		fmt.Fprintf(buf, "%g", float64(*t))
+	case *PdfObjectName:
+		buf.WriteString("/")
+		buf.WriteString(string(*t))
+	case *PdfObjectString:
+		buf.WriteString("(")
+		for _, b := range []byte(*t) {
+			switch b {
+			case '(', ')', '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(b)
+			default:
+				buf.WriteByte(b)
+			}
+		}
+		buf.WriteString(")")
+	case *PdfObjectHexString:
+		buf.WriteString("<")
+		fmt.Fprintf(buf, "%x", []byte(*t))
+		buf.WriteString(">")
+	case *PdfObjectRaw:
+		buf.WriteString(string(*t))
+	case *PdfObjectReference:
+		fmt.Fprintf(buf, "%d %d R", t.ObjectNumber, t.GenerationNumber)
+	case *PdfObjectArray:
+		buf.WriteString("[")
+		for i, elem := range *t {
+			if i > 0 {
+				buf.WriteString(" ")
+			}
+			if err := writeObject(buf, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("]")
+	case *PdfObjectDictionary:
+		return writeDict(buf, t)
+	case *PdfObjectStream:
+		if err := writeDict(buf, t.PdfObjectDictionary); err != nil {
+			return err
+		}
+		buf.WriteString("\nstream\n")
+		buf.Write(t.Stream)
+		buf.WriteString("\nendstream")
+	default:
+		return fmt.Errorf("serializeObject: unsupported object type %T", obj)
+	}
+	return nil
+}
+
+// PdfObjectHexString represents a PDF string object written using hexadecimal notation
+// (`<4E6F>`) rather than the literal `(...)` syntax PdfObjectString uses. It is primarily used
+// for binary content such as a signature dictionary's `/Contents` entry, where the hex form
+// lets a fixed-width placeholder be reserved and later patched in place without resizing the
+// surrounding object.
+type PdfObjectHexString []byte
+
+// MakeHexString returns a PdfObjectHexString wrapping `data`.
+func MakeHexString(data []byte) *PdfObjectHexString {
+	hs := PdfObjectHexString(append([]byte(nil), data...))
+	return &hs
+}
+
+// PdfObjectRaw wraps a pre-formatted string that is emitted into the output verbatim, with no
+// further escaping or conversion. It exists for object fields, such as a signature's
+// /ByteRange, that must reserve a fixed-width placeholder representation which is patched in
+// place by byte offset once those offsets are known, rather than re-serialized from scratch.
+type PdfObjectRaw string
+
+// MakeRaw returns a PdfObjectRaw wrapping `s`.
+func MakeRaw(s string) *PdfObjectRaw {
+	r := PdfObjectRaw(s)
+	return &r
+}
+
+func writeDict(buf *bytes.Buffer, dict *PdfObjectDictionary) error {
+	buf.WriteString("<<")
+	for _, key := range dict.Keys() {
+		buf.WriteString(" ")
+		buf.WriteString("/")
+		buf.WriteString(string(key))
+		buf.WriteString(" ")
+		if err := writeObject(buf, dict.Get(key)); err != nil {
+			return err
+		}
+	}
+	buf.WriteString(" >>")
+	return nil
+}
@@ -69,9 +69,9 @@ func NewEncoderFromStream(streamObj *PdfObjectStream) (StreamEncoder, error) {
 	} else if *method == StreamEncodingFilterNameASCII85 {
 		return NewASCII85Encoder(), nil
 	} else if *method == StreamEncodingFilterNameCCITTFax {
-		return NewCCITTFaxEncoder(), nil
+		return newCCITTFaxEncoderFromStream(streamObj)
 	} else if *method == StreamEncodingFilterNameJBIG2 {
-		return NewJBIG2Encoder(), nil
+		return newJBIG2EncoderFromStream(streamObj)
 	} else if *method == StreamEncodingFilterNameJPX {
 		return NewJPXEncoder(), nil
 	} else {
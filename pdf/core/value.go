@@ -0,0 +1,245 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf16"
+
+	"github.com/unidoc/unidoc/common"
+)
+
+// ValueKind identifies the underlying type a Value wraps, in the style of encoding/json's
+// or rsc.io/pdf's Kind enumerations.
+type ValueKind int
+
+const (
+	// Null is the kind of a Value wrapping a nil object, a *PdfObjectNull, or an unresolved
+	// (e.g. dangling) reference.
+	Null ValueKind = iota
+	Bool
+	Integer
+	Real
+	String
+	Name
+	Array
+	Dict
+	Stream
+)
+
+// Value is a read-only, panic-free view over a PdfObject. It transparently resolves indirect
+// references and lets callers chain field/array access without juggling type assertions, e.g.:
+//
+//	root.Key("Pages").Key("Kids").Index(0).Key("MediaBox").Index(2).Float64()
+//
+// Every accessor returns the zero value for its type when `Kind()` doesn't match, rather than
+// panicking, so a chain like the one above is safe to write even against malformed PDFs.
+type Value struct {
+	parser *PdfParser
+	obj    PdfObject
+}
+
+// NewValue wraps `obj` as a Value, resolving it via `parser` if it turns out to be an indirect
+// reference. `parser` may be nil, in which case references cannot be resolved and are treated
+// as Null.
+func NewValue(parser *PdfParser, obj PdfObject) Value {
+	v := Value{parser: parser, obj: obj}
+	return v.resolve()
+}
+
+// resolve dereferences `v.obj` through the parser's Trace, if `v.obj` is a reference.
+func (v Value) resolve() Value {
+	if v.obj == nil {
+		return Value{parser: v.parser}
+	}
+
+	if _, isRef := v.obj.(*PdfObjectReference); isRef && v.parser != nil {
+		traced, err := v.parser.Trace(v.obj)
+		if err != nil {
+			return Value{parser: v.parser}
+		}
+		v.obj = traced
+	}
+
+	return v
+}
+
+// Kind returns the kind of value `v` wraps.
+func (v Value) Kind() ValueKind {
+	switch v.obj.(type) {
+	case *PdfObjectBool:
+		return Bool
+	case *PdfObjectInteger:
+		return Integer
+	case *PdfObjectFloat:
+		return Real
+	case *PdfObjectString:
+		return String
+	case *PdfObjectName:
+		return Name
+	case *PdfObjectArray:
+		return Array
+	case *PdfObjectStream:
+		return Stream
+	case *PdfObjectDictionary:
+		return Dict
+	default:
+		return Null
+	}
+}
+
+// Bool returns the value as a bool, or false if `v` does not wrap a boolean.
+func (v Value) Bool() bool {
+	b, ok := v.obj.(*PdfObjectBool)
+	if !ok {
+		return false
+	}
+	return bool(*b)
+}
+
+// Int64 returns the value as an int64, or 0 if `v` does not wrap an integer.
+func (v Value) Int64() int64 {
+	i, ok := v.obj.(*PdfObjectInteger)
+	if !ok {
+		return 0
+	}
+	return int64(*i)
+}
+
+// Float64 returns the value as a float64. It accepts both PdfObjectFloat and PdfObjectInteger,
+// since PDF numeric objects are interchangeable in practice (e.g. a MediaBox entry written as
+// an integer). Returns 0 if `v` wraps neither.
+func (v Value) Float64() float64 {
+	switch obj := v.obj.(type) {
+	case *PdfObjectFloat:
+		return float64(*obj)
+	case *PdfObjectInteger:
+		return float64(*obj)
+	default:
+		return 0
+	}
+}
+
+// Name returns the value as a name (without the leading `/`), or "" if `v` does not wrap a name.
+func (v Value) Name() string {
+	n, ok := v.obj.(*PdfObjectName)
+	if !ok {
+		return ""
+	}
+	return string(*n)
+}
+
+// RawString returns the raw bytes of a string object as a Go string, with no interpretation of
+// PDFDocEncoding/UTF-16BE. Returns "" if `v` does not wrap a string.
+func (v Value) RawString() string {
+	s, ok := v.obj.(*PdfObjectString)
+	if !ok {
+		return ""
+	}
+	return string(*s)
+}
+
+// TextString returns the value decoded as a PDF text string, honoring the UTF-16BE byte-order
+// mark (0xFE 0xFF) used to distinguish Unicode text strings from PDFDocEncoded ones. Returns ""
+// if `v` does not wrap a string.
+func (v Value) TextString() string {
+	s, ok := v.obj.(*PdfObjectString)
+	if !ok {
+		return ""
+	}
+
+	raw := []byte(*s)
+	if len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF {
+		raw = raw[2:]
+		runes := make([]uint16, 0, len(raw)/2)
+		for i := 0; i+1 < len(raw); i += 2 {
+			runes = append(runes, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		return string(utf16.Decode(runes))
+	}
+
+	// PDFDocEncoding is ASCII-compatible for the common case; fall back to raw bytes.
+	return string(raw)
+}
+
+// Len returns the number of entries in an array, or the number of key/value pairs in a
+// dictionary (or a stream's dictionary). Returns 0 for any other kind.
+func (v Value) Len() int {
+	switch obj := v.obj.(type) {
+	case *PdfObjectArray:
+		return len(*obj)
+	case *PdfObjectDictionary:
+		return len(obj.Keys())
+	case *PdfObjectStream:
+		return len(obj.PdfObjectDictionary.Keys())
+	default:
+		return 0
+	}
+}
+
+// Index returns the i'th element of an array value, resolving references. Returns a Null Value
+// if `v` is not an array or `i` is out of range.
+func (v Value) Index(i int) Value {
+	arr, ok := v.obj.(*PdfObjectArray)
+	if !ok || i < 0 || i >= len(*arr) {
+		return Value{parser: v.parser}
+	}
+	return NewValue(v.parser, (*arr)[i])
+}
+
+// Key looks up `name` in a dictionary value (or a stream's dictionary), resolving references.
+// Returns a Null Value if `v` is not a dictionary/stream or the key is absent.
+func (v Value) Key(name string) Value {
+	var dict *PdfObjectDictionary
+	switch obj := v.obj.(type) {
+	case *PdfObjectDictionary:
+		dict = obj
+	case *PdfObjectStream:
+		dict = obj.PdfObjectDictionary
+	default:
+		return Value{parser: v.parser}
+	}
+
+	entry := dict.Get(PdfObjectName(name))
+	if entry == nil {
+		return Value{parser: v.parser}
+	}
+	return NewValue(v.parser, entry)
+}
+
+// SameObject reports whether `v` and `other` resolve to the same underlying PdfObject, e.g. to
+// check whether two indirect references found via different paths (such as /Perms/DocMDP and an
+// /AcroForm field's /V) point at the same signature dictionary.
+func (v Value) SameObject(other Value) bool {
+	return v.obj != nil && v.obj == other.obj
+}
+
+// Reader returns a ReadCloser over the stream's decoded content, applying whatever filter
+// chain `NewEncoderFromStream` resolves for it. Returns nil if `v` does not wrap a stream, or
+// if decoding fails.
+func (v Value) Reader() io.ReadCloser {
+	stream, ok := v.obj.(*PdfObjectStream)
+	if !ok {
+		return nil
+	}
+
+	decoded, err := DecodeStream(stream)
+	if err != nil {
+		common.Log.Debug("Value.Reader: failed decoding stream: %v", err)
+		return nil
+	}
+
+	return nopReadCloser{bytes.NewReader(decoded)}
+}
+
+// nopReadCloser adapts an io.Reader into an io.ReadCloser whose Close is a no-op, since decoded
+// stream bytes are held in memory rather than backed by an open file/descriptor.
+type nopReadCloser struct {
+	io.Reader
+}
+
+func (nopReadCloser) Close() error { return nil }
@@ -0,0 +1,212 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/unidoc/unidoc/common"
+)
+
+// LoadXrefAt is the single entry point for parsing a cross-reference section starting at file
+// offset `offset` (normally the value read from `startxref`, or from a `/Prev` key). It peeks
+// at what's there and dispatches to the classic `xref` table parser or to parseXrefStream,
+// following `/Prev` chains of either kind transparently to the caller. parser.go's xref-loading
+// loop must call this instead of assuming every section is a classic table, or PDF 1.5+
+// producers that only emit cross-reference streams (Cairo >= 1.17.5, recent LibreOffice, etc.)
+// will fail to load.
+func (this *PdfParser) LoadXrefAt(offset int64) (*PdfObjectDictionary, error) {
+	this.SetFileOffset(offset)
+
+	peeked, err := this.reader.Peek(20)
+	if err != nil && len(peeked) == 0 {
+		return nil, fmt.Errorf("failed peeking xref section at offset %d: %w", offset, err)
+	}
+
+	if looksLikeClassicXrefTable(peeked) {
+		return this.parseXrefTable()
+	}
+
+	return this.parseXrefStreamAt(offset)
+}
+
+// looksLikeClassicXrefTable reports whether `peeked` (read from the start of a cross-reference
+// section) begins with the classic `xref` keyword, as opposed to an indirect object (`N G obj`)
+// introducing a cross-reference stream.
+func looksLikeClassicXrefTable(peeked []byte) bool {
+	trimmed := peeked
+	for len(trimmed) > 0 && (trimmed[0] == ' ' || trimmed[0] == '\r' || trimmed[0] == '\n' || trimmed[0] == '\t') {
+		trimmed = trimmed[1:]
+	}
+	return len(trimmed) >= 4 && string(trimmed[:4]) == "xref"
+}
+
+// isXrefStreamObject checks whether the indirect object found at the current xref offset is a
+// cross-reference stream rather than a classic `xref` table. PDF 1.5+ producers (Cairo, recent
+// LibreOffice, etc.) may use `/Type /XRef` streams in place of, or chained with, `xref` tables.
+func (this *PdfParser) isXrefStreamObject(obj PdfObject) bool {
+	stream, ok := obj.(*PdfObjectStream)
+	if !ok {
+		return false
+	}
+
+	name, ok := stream.PdfObjectDictionary.Get("Type").(*PdfObjectName)
+	return ok && *name == "XRef"
+}
+
+// parseXrefStream parses the cross-reference stream `xstm`, merging its entries into
+// `this.xrefs` and following its `/Prev` chain. It returns the stream dictionary, which takes
+// the place of the classic trailer dictionary for xref streams.
+func (this *PdfParser) parseXrefStream(xstm *PdfObjectStream) (*PdfObjectDictionary, error) {
+	dict := xstm.PdfObjectDictionary
+
+	wArr, ok := dict.Get("W").(*PdfObjectArray)
+	if !ok || len(*wArr) != 3 {
+		return nil, errors.New("invalid or missing /W in xref stream")
+	}
+
+	widths := make([]int64, 3)
+	for i, w := range *wArr {
+		n, ok := w.(*PdfObjectInteger)
+		if !ok {
+			return nil, fmt.Errorf("invalid /W entry at index %d", i)
+		}
+		widths[i] = int64(*n)
+	}
+	w1, w2, w3 := widths[0], widths[1], widths[2]
+	entryLen := w1 + w2 + w3
+
+	size, ok := dict.Get("Size").(*PdfObjectInteger)
+	if !ok {
+		return nil, errors.New("missing /Size in xref stream")
+	}
+
+	// /Index defaults to [0 Size] when absent.
+	var index []int64
+	if idxArr, ok := dict.Get("Index").(*PdfObjectArray); ok {
+		for _, obj := range *idxArr {
+			n, ok := obj.(*PdfObjectInteger)
+			if !ok {
+				return nil, errors.New("invalid /Index entry in xref stream")
+			}
+			index = append(index, int64(*n))
+		}
+	} else {
+		index = []int64{0, int64(*size)}
+	}
+	if len(index)%2 != 0 {
+		return nil, errors.New("invalid /Index in xref stream: odd number of entries")
+	}
+
+	data, err := DecodeStream(xstm)
+	if err != nil {
+		common.Log.Error("Failed decoding xref stream: %v", err)
+		return nil, err
+	}
+
+	pos := 0
+	for s := 0; s < len(index); s += 2 {
+		first := index[s]
+		count := index[s+1]
+
+		for i := int64(0); i < count; i++ {
+			if pos+int(entryLen) > len(data) {
+				return nil, errors.New("xref stream data too short for declared /Index ranges")
+			}
+
+			objNum := int(first + i)
+			entry := data[pos : pos+int(entryLen)]
+			pos += int(entryLen)
+
+			fields := decodeXrefStreamFields(entry, widths)
+
+			// Field 1 (type) defaults to 1 when /W gives it zero width.
+			xtype := fields[0]
+			if w1 == 0 {
+				xtype = 1
+			}
+
+			switch xtype {
+			case 0:
+				// Free entry: nothing to record.
+				continue
+			case 1:
+				if _, exists := this.xrefs[objNum]; exists {
+					// Entries from a more recent xref section take precedence over /Prev chains.
+					continue
+				}
+				this.xrefs[objNum] = XrefObject{
+					XREF_TABLE_ENTRY,
+					objNum,
+					int(fields[2]),
+					fields[1],
+					0,
+					0,
+				}
+			case 2:
+				if _, exists := this.xrefs[objNum]; exists {
+					continue
+				}
+				this.xrefs[objNum] = XrefObject{
+					XREF_OBJECT_STREAM,
+					objNum,
+					0,
+					0,
+					int(fields[1]),
+					int(fields[2]),
+				}
+			default:
+				common.Log.Debug("Unknown xref stream entry type %d for object %d, skipping", xtype, objNum)
+			}
+		}
+	}
+
+	if prev, ok := dict.Get("Prev").(*PdfObjectInteger); ok {
+		// /Prev may point at either another xref stream or a classic table (a hybrid-reference
+		// file, PDF32000 7.5.8.4), so go through the same dispatch as the initial section.
+		if _, err := this.LoadXrefAt(int64(*prev)); err != nil {
+			common.Log.Debug("Failed following /Prev xref section at %d: %v", int64(*prev), err)
+		}
+	}
+
+	return dict, nil
+}
+
+// parseXrefStreamAt seeks to `offset`, parses the indirect object found there as a
+// cross-reference stream and merges its entries into the parser's xref table.
+func (this *PdfParser) parseXrefStreamAt(offset int64) (*PdfObjectDictionary, error) {
+	this.SetFileOffset(offset)
+
+	obj, err := this.ParseIndirectObject()
+	if err != nil {
+		common.Log.Error("Failed to parse xref stream at offset %d: %v", offset, err)
+		return nil, err
+	}
+
+	stream, ok := obj.(*PdfObjectStream)
+	if !ok {
+		return nil, fmt.Errorf("object at xref stream offset %d is not a stream", offset)
+	}
+
+	return this.parseXrefStream(stream)
+}
+
+// decodeXrefStreamFields decodes a single fixed-width xref stream row into its (up to) three
+// big-endian integer fields, per the widths given by the stream's /W array.
+func decodeXrefStreamFields(entry []byte, widths []int64) [3]int64 {
+	var fields [3]int64
+	pos := 0
+	for i, width := range widths {
+		var val int64
+		for j := int64(0); j < width; j++ {
+			val = val<<8 | int64(entry[pos])
+			pos++
+		}
+		fields[i] = val
+	}
+	return fields
+}
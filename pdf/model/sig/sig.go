@@ -0,0 +1,316 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package sig creates PKCS#7 detached digital signatures over PDF documents, using
+// core.IncrementalWriter so that signing never rewrites (and risks corrupting) the original
+// file. See Signer.Sign.
+package sig
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mozilla.org/pkcs7"
+
+	"github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/core"
+)
+
+// Subfilter names recognized by Acrobat and most PDF viewers for a PKCS#7 detached signature.
+const (
+	SubFilterAdobePKCS7Detached = "adbe.pkcs7.detached"
+	SubFilterCAdESDetached      = "ETSI.CAdES.detached"
+)
+
+// defaultContentsSize is the number of bytes reserved for the PKCS#7 SignedData blob before its
+// actual size is known. An RSA-2048 signature with a short chain comfortably fits in this; a
+// larger chain or ECDSA with a timestamp token may need more, via Signer.ContentsSize.
+const defaultContentsSize = 8192
+
+// byteRangeDigitWidth is the fixed width each of the four /ByteRange integers is padded to in
+// the placeholder, so that patching in the real offsets after signing never changes the
+// serialized length of the signature dictionary.
+const byteRangeDigitWidth = 10
+
+// TimestampFunc requests an RFC 3161 timestamp token over `messageDigest` (typically the
+// SHA-256 digest of the signature's own PKCS#7 encapsulated content) from a timestamp
+// authority, returning the raw TimeStampToken bytes to embed as an unsigned attribute.
+//
+// Setting Signer.Timestamp currently makes Sign fail: embedding the returned token requires
+// re-encoding the finished SignerInfo, which go.mozilla.org/pkcs7 doesn't support yet.
+type TimestampFunc func(messageDigest []byte) ([]byte, error)
+
+// Signer creates a detached PKCS#7 (CMS SignedData) signature over a PDF document's
+// /ByteRange-covered bytes and appends it as an incremental update.
+type Signer struct {
+	// Key signs the document digest. RSA and ECDSA keys are both supported, as accepted by
+	// go.mozilla.org/pkcs7.
+	Key crypto.Signer
+	// Certificate is the signer's X.509 certificate, embedded in the SignedData.
+	Certificate *x509.Certificate
+	// CertificateChain holds any intermediate CA certificates to embed alongside Certificate.
+	CertificateChain []*x509.Certificate
+	// SubFilter selects the signature encoding; defaults to SubFilterAdobePKCS7Detached.
+	SubFilter string
+	// ContentsSize is the number of bytes reserved for the signature in /Contents. Defaults to
+	// defaultContentsSize if zero.
+	ContentsSize int
+	// Timestamp, if set, makes Sign return an error without writing anything: no RFC 3161
+	// timestamp token is requested or embedded in the signature. See TimestampFunc.
+	Timestamp TimestampFunc
+	// Reason and Name are optional human-readable fields recorded in the signature dictionary.
+	Reason, Name string
+}
+
+// NewSigner returns a Signer using SubFilterAdobePKCS7Detached and the default /Contents size.
+func NewSigner(key crypto.Signer, cert *x509.Certificate, chain []*x509.Certificate) *Signer {
+	return &Signer{
+		Key:              key,
+		Certificate:      cert,
+		CertificateChain: chain,
+		SubFilter:        SubFilterAdobePKCS7Detached,
+		ContentsSize:     defaultContentsSize,
+	}
+}
+
+// SignatureField links a freshly-created signature dictionary into the document's form so that
+// viewers actually surface it, rather than leaving it an orphan object nothing points to: Sign
+// sets Field's /V to a reference to the new signature dictionary, appends FieldObjNum to
+// AcroForm's /Fields if it isn't already listed, and marks both dirty alongside the signature
+// itself.
+type SignatureField struct {
+	// FieldObjNum is the indirect object number of the /FT /Sig field dictionary that owns this
+	// signature.
+	FieldObjNum int
+	Field       *core.PdfObjectDictionary
+
+	// AcroFormObjNum is the indirect object number of the document's /AcroForm dictionary.
+	AcroFormObjNum int
+	AcroForm       *core.PdfObjectDictionary
+}
+
+// Sign reserves a /Contents placeholder and /ByteRange entry for a new signature dictionary
+// (object number `sigObjNum`), writes the resulting incremental update against `parser` to an
+// in-memory buffer, signs the bytes /ByteRange designates as a detached PKCS#7 SignedData blob,
+// patches it into the placeholder, and writes the final bytes to `out`. If `field` is non-nil,
+// the signature is also linked into the existing form field (and AcroForm) it belongs to.
+func (s *Signer) Sign(parser *core.PdfParser, sigObjNum int, field *SignatureField, out io.Writer) error {
+	if s.ContentsSize == 0 {
+		s.ContentsSize = defaultContentsSize
+	}
+	if s.SubFilter == "" {
+		s.SubFilter = SubFilterAdobePKCS7Detached
+	}
+
+	sigDict := s.buildPlaceholderDict()
+
+	writer := core.NewIncrementalWriter(parser)
+	writer.MarkDirty(sigObjNum, sigDict)
+	linkSignatureField(writer, sigObjNum, field)
+
+	var buf bytes.Buffer
+	if err := writer.Write(&buf); err != nil {
+		return fmt.Errorf("sig: failed writing incremental update: %w", err)
+	}
+	data := buf.Bytes()
+
+	contentsStart, contentsLen, err := locateHexPlaceholder(data, s.ContentsSize)
+	if err != nil {
+		return err
+	}
+
+	byteRange := [4]int64{
+		0, int64(contentsStart - 1), // up to (not including) the opening '<'.
+		int64(contentsStart + contentsLen + 1), int64(len(data) - (contentsStart + contentsLen + 1)),
+	}
+
+	if err := patchByteRangePlaceholder(data, byteRange); err != nil {
+		return err
+	}
+
+	var content bytes.Buffer
+	content.Write(data[byteRange[0] : byteRange[0]+byteRange[1]])
+	content.Write(data[byteRange[2] : byteRange[2]+byteRange[3]])
+
+	signed, err := s.signContent(content.Bytes())
+	if err != nil {
+		return err
+	}
+	if len(signed) > s.ContentsSize {
+		return fmt.Errorf("sig: PKCS7 signature is %d bytes, exceeds reserved /Contents size of %d; increase Signer.ContentsSize", len(signed), s.ContentsSize)
+	}
+
+	hexSig := make([]byte, s.ContentsSize*2)
+	for i := range hexSig {
+		hexSig[i] = '0'
+	}
+	hex.Encode(hexSig, signed)
+	copy(data[contentsStart:contentsStart+contentsLen], hexSig)
+
+	if _, err := out.Write(data); err != nil {
+		return err
+	}
+
+	common.Log.Debug("sig: wrote %d-byte PKCS7 signature (reserved %d)", len(signed), s.ContentsSize)
+	return nil
+}
+
+// buildPlaceholderDict builds the /Sig field dictionary with a zero-filled /Contents hex
+// placeholder and a fixed-width /ByteRange placeholder, ready to be located and patched once
+// the incremental update has been serialized.
+func (s *Signer) buildPlaceholderDict() *core.PdfObjectDictionary {
+	dict := core.MakeDict()
+	dict.Set("Type", core.MakeName("Sig"))
+	dict.Set("Filter", core.MakeName("Adobe.PPKLite"))
+	dict.Set("SubFilter", core.MakeName(s.SubFilter))
+	dict.Set("M", core.MakeString(pdfDate(time.Now())))
+	if s.Reason != "" {
+		dict.Set("Reason", core.MakeString(s.Reason))
+	}
+	if s.Name != "" {
+		dict.Set("Name", core.MakeString(s.Name))
+	}
+
+	dict.Set("Contents", core.MakeHexString(make([]byte, s.ContentsSize)))
+	dict.Set("ByteRange", core.MakeRaw(byteRangePlaceholder()))
+
+	return dict
+}
+
+// linkSignatureField sets field.Field's /V to a reference to the new signature dictionary
+// `sigObjNum`, appends field.FieldObjNum to field.AcroForm's /Fields array if it isn't already
+// present, and marks both modified objects dirty. It is a no-op if `field` is nil.
+func linkSignatureField(writer *core.IncrementalWriter, sigObjNum int, field *SignatureField) {
+	if field == nil {
+		return
+	}
+
+	field.Field.Set("V", &core.PdfObjectReference{ObjectNumber: int64(sigObjNum)})
+	writer.MarkDirty(field.FieldObjNum, field.Field)
+
+	if field.AcroForm == nil {
+		return
+	}
+
+	fields, _ := field.AcroForm.Get("Fields").(*core.PdfObjectArray)
+	if fields == nil {
+		fields = &core.PdfObjectArray{}
+	}
+
+	alreadyListed := false
+	for _, elem := range *fields {
+		if ref, ok := elem.(*core.PdfObjectReference); ok && ref.ObjectNumber == int64(field.FieldObjNum) {
+			alreadyListed = true
+			break
+		}
+	}
+	if !alreadyListed {
+		*fields = append(*fields, &core.PdfObjectReference{ObjectNumber: int64(field.FieldObjNum)})
+		field.AcroForm.Set("Fields", fields)
+	}
+
+	writer.MarkDirty(field.AcroFormObjNum, field.AcroForm)
+}
+
+// byteRangePlaceholder renders a /ByteRange array with four byteRangeDigitWidth-wide zero
+// placeholders, so that the dictionary's serialized length doesn't change once real offsets
+// are known.
+func byteRangePlaceholder() string {
+	zero := strings.Repeat("0", byteRangeDigitWidth)
+	return fmt.Sprintf("[%s %s %s %s]", zero, zero, zero, zero)
+}
+
+// patchByteRangePlaceholder overwrites the /ByteRange placeholder in `data` (found by searching
+// for its known zero-filled form) with the real offsets, zero-padded to the same fixed width.
+func patchByteRangePlaceholder(data []byte, byteRange [4]int64) error {
+	placeholder := []byte(byteRangePlaceholder())
+	idx := bytes.Index(data, placeholder)
+	if idx < 0 {
+		return errors.New("sig: could not locate /ByteRange placeholder to patch")
+	}
+
+	var real strings.Builder
+	real.WriteByte('[')
+	for i, v := range byteRange {
+		if i > 0 {
+			real.WriteByte(' ')
+		}
+		digits := strconv.FormatInt(v, 10)
+		if len(digits) > byteRangeDigitWidth {
+			return fmt.Errorf("sig: /ByteRange value %d doesn't fit in %d digits", v, byteRangeDigitWidth)
+		}
+		real.WriteString(strings.Repeat("0", byteRangeDigitWidth-len(digits)))
+		real.WriteString(digits)
+	}
+	real.WriteByte(']')
+
+	copy(data[idx:idx+len(placeholder)], real.String())
+	return nil
+}
+
+// locateHexPlaceholder finds the zero-filled /Contents hex placeholder of `size` bytes within
+// `data` and returns the buffer offset of its first hex digit and the number of hex digits
+// (i.e. excluding the surrounding `<`/`>`).
+func locateHexPlaceholder(data []byte, size int) (start, length int, err error) {
+	placeholder := bytes.Repeat([]byte("00"), size)
+	idx := bytes.Index(data, placeholder)
+	if idx < 0 {
+		return 0, 0, errors.New("sig: could not locate /Contents placeholder to patch")
+	}
+	return idx, len(placeholder), nil
+}
+
+// signContent produces a detached PKCS#7 SignedData blob over `content` (the actual
+// /ByteRange-designated document bytes, not a pre-computed digest of them: pkcs7.NewSignedData
+// hashes its argument itself when building the SignerInfo's message digest) using s.Key and
+// s.Certificate.
+func (s *Signer) signContent(content []byte) ([]byte, error) {
+	if s.Timestamp != nil {
+		// Embedding an RFC 3161 timestamp token requires re-encoding the finished SignerInfo with
+		// the token folded in as an unsigned attribute (PAdES-T), which go.mozilla.org/pkcs7 has
+		// no support for. Rather than silently produce a signature that looks timestamped but
+		// isn't, fail loudly so callers notice before shipping an unsupported configuration.
+		return nil, errors.New("sig: Signer.Timestamp is set but embedding a timestamp token is not yet supported")
+	}
+
+	signedData, err := pkcs7.NewSignedData(content)
+	if err != nil {
+		return nil, fmt.Errorf("sig: failed initializing PKCS7 SignedData: %w", err)
+	}
+	signedData.Detached()
+
+	if err := signedData.AddSigner(s.Certificate, s.Key, pkcs7.SignerInfoConfig{}); err != nil {
+		return nil, fmt.Errorf("sig: failed adding signer: %w", err)
+	}
+	for _, cert := range s.CertificateChain {
+		signedData.AddCertificate(cert)
+	}
+
+	signed, err := signedData.Finish()
+	if err != nil {
+		return nil, fmt.Errorf("sig: failed finishing PKCS7 SignedData: %w", err)
+	}
+
+	return signed, nil
+}
+
+// pdfDate formats `t` as a PDF date string, D:YYYYMMDDHHmmSSOHH'mm'.
+func pdfDate(t time.Time) string {
+	_, offset := t.Zone()
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	return fmt.Sprintf("D:%s%s%02d'%02d'", t.Format("20060102150405"), sign, offset/3600, (offset%3600)/60)
+}
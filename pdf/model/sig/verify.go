@@ -0,0 +1,137 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package sig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"go.mozilla.org/pkcs7"
+
+	"github.com/unidoc/unidoc/pdf/core"
+)
+
+// VerificationResult reports the outcome of verifying a single /Sig field.
+type VerificationResult struct {
+	// SubFilter is the signature's /SubFilter value.
+	SubFilter string
+	// CoversWholeDocument is true if this signature's /ByteRange spans the entire file, i.e.
+	// no further incremental updates were appended after it (the common case for the last, or
+	// only, signature on a document).
+	CoversWholeDocument bool
+	// DigestOK is true if the embedded PKCS7 SignedData digest matches the /ByteRange bytes.
+	DigestOK bool
+	// IsDocMDP is true if this is the document's certifying signature, i.e. the catalog's
+	// /Perms /DocMDP entry points at it.
+	IsDocMDP bool
+	// DocMDPPermission is the /P value (1: no changes, 2: form fill-in/signing only, 3: also
+	// annotations) from this signature's /Reference DocMDP transform, when IsDocMDP is true.
+	DocMDPPermission int64
+	// DocMDPViolated is true if IsDocMDP, DocMDPPermission == 1 (no changes allowed), and
+	// further incremental updates were appended after this signature anyway.
+	DocMDPViolated bool
+}
+
+// VerifySignature checks the signature field at `sigValue` (typically
+// `root.Key("AcroForm").Key("Fields").Index(i).Key("V")`) against the raw document bytes
+// `fileData`: it re-derives the /ByteRange-covered content and compares it with the digest
+// embedded in the signature's PKCS7 SignedData. `root` is the document catalog, used to check
+// whether `sigValue` is the certifying (/Perms /DocMDP) signature and, if so, whether its
+// permission level was honored.
+func VerifySignature(sigValue core.Value, root core.Value, fileData []byte) (*VerificationResult, error) {
+	byteRangeVal := sigValue.Key("ByteRange")
+	if byteRangeVal.Kind() != core.Array || byteRangeVal.Len() != 4 {
+		return nil, errors.New("sig: signature field has no valid /ByteRange")
+	}
+
+	var br [4]int64
+	for i := 0; i < 4; i++ {
+		br[i] = byteRangeVal.Index(i).Int64()
+	}
+	if br[0] < 0 || br[1] < 0 || br[2] < 0 || br[3] < 0 ||
+		br[0]+br[1] > int64(len(fileData)) || br[2]+br[3] > int64(len(fileData)) {
+		return nil, fmt.Errorf("sig: /ByteRange %v out of bounds for a %d-byte file", br, len(fileData))
+	}
+
+	contentsVal := sigValue.Key("Contents")
+	if contentsVal.Kind() != core.String {
+		return nil, errors.New("sig: signature field has no /Contents")
+	}
+	signed := []byte(contentsVal.RawString())
+
+	p7, err := pkcs7.Parse(signed)
+	if err != nil {
+		return nil, fmt.Errorf("sig: failed parsing PKCS7 signature: %w", err)
+	}
+
+	var content bytes.Buffer
+	content.Write(fileData[br[0] : br[0]+br[1]])
+	content.Write(fileData[br[2] : br[2]+br[3]])
+
+	p7.Content = content.Bytes()
+	digestOK := p7.Verify() == nil
+
+	coversWholeDocument := br[2]+br[3] == int64(len(fileData))
+
+	result := &VerificationResult{
+		SubFilter:           sigValue.Key("SubFilter").Name(),
+		CoversWholeDocument: coversWholeDocument,
+		DigestOK:            digestOK,
+	}
+
+	docMDP := root.Key("Perms").Key("DocMDP")
+	if docMDP.Kind() == core.Dict && docMDP.SameObject(sigValue) {
+		result.IsDocMDP = true
+		result.DocMDPPermission = docMDPPermission(sigValue)
+		result.DocMDPViolated = result.DocMDPPermission == 1 && !coversWholeDocument
+	}
+
+	return result, nil
+}
+
+// docMDPPermission reads the /P (permission level) integer out of a certifying signature's
+// DocMDP transform, per PDF32000 Table 254/255: sigValue.Reference[i].TransformParams.P for the
+// entry whose TransformMethod is /DocMDP. Defaults to 2 (the spec's fallback) if not found.
+func docMDPPermission(sigValue core.Value) int64 {
+	refs := sigValue.Key("Reference")
+	for i := 0; i < refs.Len(); i++ {
+		ref := refs.Index(i)
+		if ref.Key("TransformMethod").Name() != "DocMDP" {
+			continue
+		}
+		if p := ref.Key("TransformParams").Key("P"); p.Kind() == core.Integer {
+			return p.Int64()
+		}
+	}
+	return 2
+}
+
+// VerifyAll walks every signature field under the AcroForm (via `formFields`, each expected to
+// be a /Sig field's dictionary Value) and verifies them against `fileData`, returning one
+// VerificationResult per signature in field order. Multiple signatures appear as a chain of
+// incremental updates, each signature's /ByteRange covering everything written before it,
+// earlier signatures included; only the most recently appended signature reaches the end of
+// `fileData`, earlier ones do not (see CoversWholeDocument).
+func VerifyAll(formFields []core.Value, root core.Value, fileData []byte) ([]*VerificationResult, error) {
+	var results []*VerificationResult
+	for i, field := range formFields {
+		if field.Key("FT").Name() != "Sig" {
+			continue
+		}
+		value := field.Key("V")
+		if value.Kind() != core.Dict {
+			continue
+		}
+
+		result, err := VerifySignature(value, root, fileData)
+		if err != nil {
+			return results, fmt.Errorf("sig: field %d: %w", i, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}